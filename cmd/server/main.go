@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"time"
+	"user-management/internal/auth"
 	"user-management/internal/config"
-	"user-management/internal/handlers"
+	"user-management/internal/events"
+	"user-management/internal/grpcserver"
 	"user-management/internal/middleware"
+	"user-management/internal/openapi"
 	"user-management/internal/repository"
+	"user-management/internal/server"
 	"user-management/internal/service"
+	"user-management/internal/stream"
+	"user-management/internal/webhooks"
 
-	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
@@ -29,23 +37,64 @@ func main(){
 		slog.Error("error while loading db","error",err)
 		os.Exit(1)
 	}
-	service:=service.NewUserService(cfg,repo)
-	handler:=handlers.NewUserHandler(service)
-
-	router:=mux.NewRouter()
-	router.HandleFunc("/users",handler.CreateHandler).Methods("POST")
-	router.HandleFunc("/auth/login",handler.LoginHandler).Methods("POST")
-	//protected routes authenticationrequired
-	protected:=router.PathPrefix("/").Subrouter()
-	protected.Use(middleware.JWTMiddleware)
-	
-	protected.HandleFunc("/users",handler.GetAllHandler).Methods("GET")
-	protected.HandleFunc("/users/{id:[0-9]+}",handler.GetByIDHandler).Methods("GET")
-	protected.HandleFunc("/users/{id:[0-9]+}",handler.UpdateHandler).Methods("PUT")
-	protected.HandleFunc("/users/{id:[0-9]+}",handler.DeleteHandler).Methods("DELETE")
+	policy,err:=auth.NewPasswordPolicy(cfg.PasswordPolicy)
+	if err!=nil{
+		slog.Error("error while loading password policy","error",err)
+		os.Exit(1)
+	}
+	hasher,err:=auth.NewPasswordHasher(cfg.PasswordHash)
+	if err!=nil{
+		slog.Error("error while configuring password hasher","error",err)
+		os.Exit(1)
+	}
+	userService:=service.NewUserService(cfg,repo,policy,hasher)
+
+	broker:=stream.NewBroker(cfg.Stream)
+	sinks:=[]events.Sink{events.LogSink{},broker,webhooks.NewSink(repo)}
+	if cfg.EventsWebhookURL!=""{
+		sinks=append(sinks,events.NewHTTPSink(cfg.EventsWebhookURL))
+	}
+	dispatcher:=events.NewDispatcher(repo,cfg.OutboxPollInterval,cfg.OutboxMaxAttempts,sinks...)
+	go dispatcher.Run(context.Background())
+	go webhooks.NewDeliverer(repo,cfg.Webhooks).Run(context.Background())
+
+	idempotency:=middleware.NewIdempotency(repo,cfg.IdempotencyTTL)
+	go idempotency.RunCleanup(context.Background(),time.Hour)
+
+	spec:=openapi.Spec()
+	router,err:=server.NewRouter(cfg,spec,server.Deps{
+		Users: userService,
+		Webhooks: service.NewWebhookService(repo,cfg.Webhooks),
+		Idempotency: idempotency,
+		Broker: broker,
+	})
+	if err!=nil{
+		slog.Error("error while building routes","error",err)
+		os.Exit(1)
+	}
+	//the server refuses to start if the spec and the routes differ
+	if err:=openapi.Verify(router,spec);err!=nil{
+		slog.Error("openapi spec does not match the routes","error",err)
+		os.Exit(1)
+	}
+
+	if cfg.GRPCAddr!=""{
+		listener,err:=net.Listen("tcp",cfg.GRPCAddr)
+		if err!=nil{
+			slog.Error("unable to listen for grpc","error",err,"addr",cfg.GRPCAddr)
+			os.Exit(1)
+		}
+		grpcServer:=grpcserver.New(userService,grpcserver.NewAuthenticator(cfg,userService))
+		go func(){
+			slog.Info("starting grpc server","addr",cfg.GRPCAddr)
+			if err:=grpcServer.Serve(listener);err!=nil{
+				slog.Error("grpc server stopped","error",err)
+			}
+		}()
+	}
 
 	slog.Info("starting user server","port", 8080)
-	if err:=http.ListenAndServe(":8080",router);err!=nil{
+	if err:=http.ListenAndServe(":8080",middleware.NegotiateVersion(router));err!=nil{
 		slog.Error("unable to start server","error",err,"port",8080)
 	}
 
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"user-management/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordMismatch   = errors.New("password does not match")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUnknownHash        = errors.New("unrecognized password hash format")
+)
+
+// PasswordHasher hashes passwords into self-describing strings so the
+// algorithm and cost can change without invalidating stored hashes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) error
+	NeedsRehash(hash string) bool
+}
+
+// algorithm is implemented by each concrete hashing scheme.
+type algorithm interface {
+	PasswordHasher
+	Recognizes(hash string) bool
+}
+
+// MultiHasher hashes with the configured algorithm and verifies hashes
+// produced by any supported one.
+type MultiHasher struct {
+	preferred  algorithm
+	algorithms []algorithm
+}
+
+func NewPasswordHasher(cfg config.PasswordHashConfig) (*MultiHasher, error) {
+	if err := validateHashConfig(cfg); err != nil {
+		return nil, err
+	}
+	bc := &BcryptHasher{Cost: cfg.BcryptCost}
+	ar := &Argon2idHasher{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	h := &MultiHasher{algorithms: []algorithm{ar, bc}}
+	switch cfg.Algorithm {
+	case "argon2id":
+		h.preferred = ar
+	case "bcrypt":
+		h.preferred = bc
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", cfg.Algorithm)
+	}
+	return h, nil
+}
+
+// validateHashConfig rejects costs that would wrap when converted or make
+// hashing fail or panic on every login.
+func validateHashConfig(cfg config.PasswordHashConfig) error {
+	switch {
+	case cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost:
+		return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+	case cfg.Argon2Iterations < 1 || int64(cfg.Argon2Iterations) > math.MaxUint32:
+		return fmt.Errorf("argon2 iterations must be at least 1, got %d", cfg.Argon2Iterations)
+	case cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > math.MaxUint8:
+		return fmt.Errorf("argon2 parallelism must be between 1 and %d, got %d", math.MaxUint8, cfg.Argon2Parallelism)
+	case cfg.Argon2Memory < 8*cfg.Argon2Parallelism || int64(cfg.Argon2Memory) > math.MaxUint32:
+		return fmt.Errorf("argon2 memory must be at least %d KiB (8 per lane), got %d", 8*cfg.Argon2Parallelism, cfg.Argon2Memory)
+	}
+	return nil
+}
+
+func (h *MultiHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *MultiHasher) Verify(hash, password string) error {
+	for _, a := range h.algorithms {
+		if a.Recognizes(hash) {
+			return a.Verify(hash, password)
+		}
+	}
+	return ErrUnknownHash
+}
+
+// NeedsRehash reports whether hash was made with a different algorithm or
+// with weaker parameters than the ones currently configured.
+func (h *MultiHasher) NeedsRehash(hash string) bool {
+	if !h.preferred.Recognizes(hash) {
+		return true
+	}
+	return h.preferred.NeedsRehash(hash)
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (b *BcryptHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (b *BcryptHasher) Verify(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+func (b *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < b.Cost
+}
+
+// Argon2idHasher produces PHC strings of the form
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a *Argon2idHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("unable to generate salt %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2idHasher) Verify(hash, password string) error {
+	p, err := decodeArgon2(hash)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (a *Argon2idHasher) NeedsRehash(hash string) bool {
+	p, err := decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+	return p.memory < a.Memory || p.iterations < a.Iterations || p.parallelism != a.Parallelism ||
+		uint32(len(p.key)) < a.KeyLength
+}
+
+func decodeArgon2(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownHash
+	}
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, ErrUnknownHash
+	}
+	// argon2.IDKey panics on zero iterations or parallelism
+	if p.memory == 0 || p.iterations == 0 || p.parallelism == 0 {
+		return nil, ErrUnknownHash
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownHash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, ErrUnknownHash
+	}
+	// an empty key would compare equal to the empty key derived from any password
+	if len(p.salt) == 0 || len(p.key) == 0 {
+		return nil, ErrUnknownHash
+	}
+	return p, nil
+}
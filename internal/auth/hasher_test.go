@@ -0,0 +1,36 @@
+package auth
+
+import (
+	stderrors "errors"
+	"testing"
+)
+
+func TestArgon2RejectsMalformedHashes(t *testing.T) {
+	h := &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	valid, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Verify(valid, "correct horse"); err != nil {
+		t.Fatalf("valid hash did not verify: %v", err)
+	}
+
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	for name, hash := range map[string]string{
+		"empty key":        "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$",
+		"empty salt":       "$argon2id$v=19$m=64,t=1,p=1$$" + key,
+		"zero memory":      "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key,
+		"zero iterations":  "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key,
+		"zero parallelism": "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// a panic here fails the test as well
+			if err := h.Verify(hash, "anything"); !stderrors.Is(err, ErrUnknownHash) {
+				t.Errorf("Verify = %v, want %v", err, ErrUnknownHash)
+			}
+			if !h.NeedsRehash(hash) {
+				t.Error("malformed hash does not need a rehash")
+			}
+		})
+	}
+}
\ No newline at end of file
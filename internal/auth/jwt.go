@@ -9,13 +9,30 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 )
+var ErrSessionRevoked=errors.New("session revoked")
+
+// passwordChangeTokenExpiry bounds how long a user with an expired password
+// has to complete the change.
+const passwordChangeTokenExpiry=15*time.Minute
+
 func GenerateToken(u *model.User,cfg *config.Config)(string,error){
+	return generateToken(u,cfg,"",cfg.JWTExpiry)
+}
+
+// GeneratePasswordChangeToken issues a short-lived token that only grants
+// access to the password change flow.
+func GeneratePasswordChangeToken(u *model.User,cfg *config.Config)(string,error){
+	return generateToken(u,cfg,model.ScopePasswordChange,passwordChangeTokenExpiry)
+}
+
+func generateToken(u *model.User,cfg *config.Config,scope string,expiry time.Duration)(string,error){
 	claims:=&model.AccessClaims{
 		Email: u.Email,
+		Scope: scope,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject: fmt.Sprintf("%d",u.ID),
 			IssuedAt: jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.JWTExpiry)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 		},
 	}
 	token:=jwt.NewWithClaims(jwt.SigningMethodHS512,claims)
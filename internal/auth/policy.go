@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"user-management/internal/config"
+	"user-management/internal/errors"
+)
+
+// PasswordPolicy checks candidate passwords against the configured rules
+// and an optional list of known breached passwords.
+type PasswordPolicy struct {
+	cfg      config.PasswordPolicyConfig
+	breached map[string]struct{}
+}
+
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{cfg: cfg, breached: map[string]struct{}{}}
+	if cfg.BreachedListPath == "" {
+		return p, nil
+	}
+	f, err := os.Open(cfg.BreachedListPath)
+	if err != nil {
+		slog.Error("unable to open breached password list", "error", err, "path", cfg.BreachedListPath)
+		return nil, fmt.Errorf("unable to open breached password list %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read breached password list %w", err)
+	}
+	slog.Info("breached password list loaded", "count", len(p.breached))
+	return p, nil
+}
+
+// Validate returns a ValidationError listing every rule the password breaks,
+// or nil when it satisfies the policy.
+func (p *PasswordPolicy) Validate(password, username, email string) error {
+	if violations := p.Check(password, username, email); len(violations) > 0 {
+		return errors.NewFieldValidationError(violations...)
+	}
+	return nil
+}
+
+// Check returns every rule the password breaks, for callers that merge them
+// with other validation results.
+func (p *PasswordPolicy) Check(password, username, email string) []errors.FieldViolation {
+	var violations []errors.FieldViolation
+	add := func(code, message string) {
+		violations = append(violations, errors.FieldViolation{Field: "password", Code: code, Message: message})
+	}
+	// the minimum is in characters; the maximum stays in bytes since that
+	// is where bcrypt truncates
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		add("too_short", fmt.Sprintf("must be at least %d characters", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && len(password) > p.cfg.MaxLength {
+		add("too_long", fmt.Sprintf("must be at most %d bytes", p.cfg.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		add("missing_uppercase", "must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		add("missing_lowercase", "must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		add("missing_digit", "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		add("missing_symbol", "must contain a symbol")
+	}
+
+	lower := strings.ToLower(password)
+	// very short identifiers would reject too many legitimate passwords
+	if u := strings.ToLower(strings.TrimSpace(username)); len(u) >= 3 && strings.Contains(lower, u) {
+		add("contains_username", "must not contain the username")
+	}
+	if local, _, ok := strings.Cut(strings.TrimSpace(email), "@"); ok && len(local) >= 3 && strings.Contains(lower, strings.ToLower(local)) {
+		add("contains_email", "must not contain the email address")
+	}
+	if _, found := p.breached[lower]; found {
+		add("breached", "appears in a list of breached passwords")
+	}
+
+	return violations
+}
+
+// Generate returns a random password that satisfies the policy, for accounts
+// created by provisioning systems that sign in some other way.
+func (p *PasswordPolicy) Generate() (string, error) {
+	const (
+		upper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lower   = "abcdefghijkmnopqrstuvwxyz"
+		digits  = "23456789"
+		symbols = "!#$%*+-=?@_"
+	)
+	length := max(p.cfg.MinLength, 24)
+	if p.cfg.MaxLength > 0 {
+		length = min(length, p.cfg.MaxLength)
+	}
+	// one of each class first, so every Require* rule holds
+	sets := []string{upper, lower, digits, symbols}
+	all := upper + lower + digits + symbols
+	password := make([]byte, 0, length)
+	for i := 0; len(password) < length; i++ {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", fmt.Errorf("unable to generate password %w", err)
+		}
+		password = append(password, set[n.Int64()])
+	}
+	// move the fixed classes away from the start
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("unable to generate password %w", err)
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"user-management/internal/config"
+)
+
+func TestPolicyCountsCharactersNotBytes(t *testing.T) {
+	p, err := NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := func(password string) map[string]bool {
+		found := map[string]bool{}
+		for _, v := range p.Check(password, "", "") {
+			found[v.Code] = true
+		}
+		return found
+	}
+
+	// four characters, twelve bytes
+	if !codes("ääää")["too_short"] {
+		t.Error("4 multibyte characters passed a minimum of 8 characters")
+	}
+	if codes("ääääääää")["too_short"] {
+		t.Error("8 multibyte characters were rejected as too short")
+	}
+	// 30 characters, 90 bytes: over the bcrypt limit
+	if !codes(strings.Repeat("€", 30))["too_long"] {
+		t.Error("90 bytes passed a maximum of 72 bytes")
+	}
+}
\ No newline at end of file
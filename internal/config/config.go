@@ -3,8 +3,11 @@ package config
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,6 +15,40 @@ type Config struct{
 	dburl string
 	JWTSecret string
 	JWTExpiry time.Duration
+	PasswordPolicy PasswordPolicyConfig
+	PasswordHash PasswordHashConfig
+	// LowercaseEmailLocalPart also lowercases the part before the @ when
+	// storing emails. Lookups are case-insensitive either way.
+	LowercaseEmailLocalPart bool
+	OutboxPollInterval time.Duration
+	// OutboxMaxAttempts is how many times an event is offered to the sinks
+	// before it is given up on.
+	OutboxMaxAttempts int
+	// EventsWebhookURL, when set, receives every domain event as a JSON POST.
+	EventsWebhookURL string
+	Webhooks WebhookConfig
+	// WebhooksAdminToken is the bearer token operators manage webhook
+	// subscriptions with. The /webhooks API is disabled when it is empty.
+	WebhooksAdminToken string
+	Stream StreamConfig
+	// IdempotencyTTL is how long responses to Idempotency-Key requests are replayed.
+	IdempotencyTTL time.Duration
+	Batch BatchConfig
+	Import ImportConfig
+	// SCIMToken is the bearer token of the SCIM provisioning client. The SCIM
+	// API is disabled when it is empty.
+	SCIMToken string
+	// GRPCAddr is where the gRPC API listens. It is disabled when empty.
+	GRPCAddr string
+	// IntrospectionClients maps the client ids allowed to call the token
+	// introspection endpoint to their secrets. The endpoint is disabled
+	// when it is empty.
+	IntrospectionClients map[string]string
+	// ValidateResponses checks responses against the OpenAPI document and
+	// logs any difference. Meant for development.
+	ValidateResponses bool
+	// V1 dates the deprecation of the /v1 routes.
+	V1 DeprecationConfig
 }
 
 type DatabaseConfig struct{
@@ -25,6 +62,75 @@ type DatabaseConfig struct{
 	MaxIdleConns int
 	MaxLifeTime time.Duration
 }
+
+// PasswordPolicyConfig holds the rules enforced on every new password.
+// MaxLength defaults to 72 because bcrypt silently truncates anything longer.
+type PasswordPolicyConfig struct{
+	MinLength int
+	MaxLength int
+	RequireUpper bool
+	RequireLower bool
+	RequireDigit bool
+	RequireSymbol bool
+	BreachedListPath string
+	// HistorySize is how many previous passwords cannot be reused.
+	HistorySize int
+	// MaxAge forces a password change after this long; zero disables expiry.
+	MaxAge time.Duration
+}
+// PasswordHashConfig selects the algorithm used for new hashes and its cost.
+// Hashes made with another algorithm or older costs are upgraded on login.
+type PasswordHashConfig struct{
+	Algorithm string
+	BcryptCost int
+	// Argon2Memory is in KiB. The argon2 values are range-checked by
+	// auth.NewPasswordHasher, so they are kept as read here.
+	Argon2Memory int
+	Argon2Iterations int
+	Argon2Parallelism int
+}
+// WebhookConfig controls delivery to partner webhooks registered via /webhooks.
+type WebhookConfig struct{
+	PollInterval time.Duration
+	Timeout time.Duration
+	MaxAttempts int
+	RetryBase time.Duration
+	MaxBackoff time.Duration
+	// DisableAfter consecutive failed attempts turns the webhook off.
+	DisableAfter int
+	// AllowPrivateNetworks lets webhooks point at loopback, private and
+	// link-local addresses. Only for local development.
+	AllowPrivateNetworks bool
+}
+// BatchConfig limits POST /users:batch.
+type BatchConfig struct{
+	MaxOperations int
+	// HashWorkers bounds how many passwords of one batch are hashed at once.
+	HashWorkers int
+}
+// ImportConfig controls POST /users/import.
+type ImportConfig struct{
+	// Uploads larger than AsyncThreshold bytes, or of unknown size, run as
+	// background jobs.
+	AsyncThreshold int64
+	MaxSize int64
+	// JobRetention is how long finished jobs and their reports are kept.
+	JobRetention time.Duration
+}
+// DeprecationConfig announces when an API version was deprecated and when
+// it will be removed; a zero Sunset leaves the removal date open.
+type DeprecationConfig struct{
+	DeprecatedAt time.Time
+	Sunset time.Time
+}
+// StreamConfig sizes the live event stream served at /users/events.
+type StreamConfig struct{
+	// ReplaySize is how many recent events a reconnecting client can resume from.
+	ReplaySize int
+	// SubscriberBuffer is how many events may queue for one client before it is dropped.
+	SubscriberBuffer int
+	Heartbeat time.Duration
+}
 func LoadConfig() *Config{
 	dbURL:=LoadDBConfig().GetConnectionString()
 	if dbURL==""{
@@ -36,6 +142,45 @@ func LoadConfig() *Config{
 		dburl: dbURL,
 		JWTSecret: secretkey,
 		JWTExpiry: expiry,
+		PasswordPolicy: LoadPasswordPolicyConfig(),
+		PasswordHash: LoadPasswordHashConfig(),
+		LowercaseEmailLocalPart: getEnvBool("EMAIL_LOWERCASE_LOCAL_PART",false),
+		OutboxPollInterval: getEnvPositiveDuration("OUTBOX_POLL_INTERVAL",2*time.Second),
+		OutboxMaxAttempts: getEnvPositiveInt("OUTBOX_MAX_ATTEMPTS",100),
+		EventsWebhookURL: getEnv("EVENTS_WEBHOOK_URL",""),
+		Webhooks: WebhookConfig{
+			PollInterval: getEnvPositiveDuration("WEBHOOK_POLL_INTERVAL",5*time.Second),
+			Timeout: getEnvPositiveDuration("WEBHOOK_TIMEOUT",10*time.Second),
+			MaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS",8),
+			RetryBase: getEnvPositiveDuration("WEBHOOK_RETRY_BASE",30*time.Second),
+			MaxBackoff: getEnvPositiveDuration("WEBHOOK_MAX_BACKOFF",6*time.Hour),
+			DisableAfter: getEnvInt("WEBHOOK_DISABLE_AFTER",20),
+			AllowPrivateNetworks: getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS",false),
+		},
+		WebhooksAdminToken: getEnv("WEBHOOKS_ADMIN_TOKEN",""),
+		Stream: StreamConfig{
+			ReplaySize: getEnvPositiveInt("EVENT_STREAM_REPLAY_SIZE",1000),
+			SubscriberBuffer: getEnvPositiveInt("EVENT_STREAM_BUFFER",64),
+			Heartbeat: getEnvPositiveDuration("EVENT_STREAM_HEARTBEAT",15*time.Second),
+		},
+		IdempotencyTTL: getEnvPositiveDuration("IDEMPOTENCY_TTL",24*time.Hour),
+		Import: ImportConfig{
+			AsyncThreshold: int64(getEnvInt("IMPORT_ASYNC_THRESHOLD",1<<20)),
+			MaxSize: int64(getEnvInt("IMPORT_MAX_SIZE",100<<20)),
+			JobRetention: getEnvPositiveDuration("IMPORT_JOB_RETENTION",24*time.Hour),
+		},
+		Batch: BatchConfig{
+			MaxOperations: getEnvInt("BATCH_MAX_OPERATIONS",1000),
+			HashWorkers: getEnvInt("BATCH_HASH_WORKERS",runtime.NumCPU()),
+		},
+		SCIMToken: getEnv("SCIM_BEARER_TOKEN",""),
+		GRPCAddr: getEnv("GRPC_ADDR",""),
+		IntrospectionClients: getEnvPairs("INTROSPECTION_CLIENTS"),
+		ValidateResponses: getEnv("APP_ENV","production")=="development",
+		V1: DeprecationConfig{
+			DeprecatedAt: getEnvDate("API_V1_DEPRECATED_AT",time.Date(2026,10,19,0,0,0,0,time.UTC)),
+			Sunset: getEnvDate("API_V1_SUNSET",time.Time{}),
+		},
 	}
 }
 func LoadDBConfig() *DatabaseConfig{
@@ -52,6 +197,28 @@ func LoadDBConfig() *DatabaseConfig{
 		MaxLifeTime:5*time.Minute,
 	}
 }
+func LoadPasswordPolicyConfig() PasswordPolicyConfig{
+	return PasswordPolicyConfig{
+		MinLength: getEnvInt("PASSWORD_MIN_LENGTH",8),
+		MaxLength: getEnvInt("PASSWORD_MAX_LENGTH",72),
+		RequireUpper: getEnvBool("PASSWORD_REQUIRE_UPPER",true),
+		RequireLower: getEnvBool("PASSWORD_REQUIRE_LOWER",true),
+		RequireDigit: getEnvBool("PASSWORD_REQUIRE_DIGIT",true),
+		RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL",false),
+		BreachedListPath: getEnv("PASSWORD_BREACHED_LIST",""),
+		HistorySize: getEnvInt("PASSWORD_HISTORY_SIZE",5),
+		MaxAge: getEnvDuration("PASSWORD_MAX_AGE",0),
+	}
+}
+func LoadPasswordHashConfig() PasswordHashConfig{
+	return PasswordHashConfig{
+		Algorithm: getEnv("PASSWORD_HASH_ALGORITHM","argon2id"),
+		BcryptCost: getEnvInt("BCRYPT_COST",10),
+		Argon2Memory: getEnvInt("ARGON2_MEMORY_KB",64*1024),
+		Argon2Iterations: getEnvInt("ARGON2_ITERATIONS",3),
+		Argon2Parallelism: getEnvInt("ARGON2_PARALLELISM",2),
+	}
+}
 func (cfg *DatabaseConfig) GetConnectionString()string{
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 cfg.Host,cfg.Port,cfg.User,cfg.Password,cfg.DatabaseName,cfg.SSLMode)
@@ -62,4 +229,78 @@ func getEnv(value string,def string)string{
 		return val
 	}
 	return def
+}
+
+func getEnvInt(value string,def int)int{
+	n,err:=strconv.Atoi(getEnv(value,""))
+	if err!=nil{
+		return def
+	}
+	return n
+}
+
+// getEnvPositiveInt is getEnvInt for settings where zero or less would
+// break the feature; such values are replaced by def with a warning.
+func getEnvPositiveInt(value string,def int)int{
+	n:=getEnvInt(value,def)
+	if n<1{
+		slog.Warn("ignoring non-positive setting","variable",value,"default",def)
+		return def
+	}
+	return n
+}
+
+// getEnvPositiveDuration is the same for intervals and timeouts; a zero
+// ticker interval panics.
+func getEnvPositiveDuration(value string,def time.Duration)time.Duration{
+	d:=getEnvDuration(value,def)
+	if d<=0{
+		slog.Warn("ignoring non-positive setting","variable",value,"default",def.String())
+		return def
+	}
+	return d
+}
+
+func getEnvBool(value string,def bool)bool{
+	b,err:=strconv.ParseBool(getEnv(value,""))
+	if err!=nil{
+		return def
+	}
+	return b
+}
+
+func getEnvDuration(value string,def time.Duration)time.Duration{
+	d,err:=time.ParseDuration(getEnv(value,""))
+	if err!=nil{
+		return def
+	}
+	return d
+}
+
+// getEnvDate reads a date like 2027-04-19, taken as midnight UTC.
+func getEnvDate(value string,def time.Time)time.Time{
+	t,err:=time.Parse(time.DateOnly,getEnv(value,""))
+	if err!=nil{
+		return def
+	}
+	return t
+}
+
+// getEnvPairs reads a comma separated list of key:value pairs. Malformed
+// entries are skipped with a warning.
+func getEnvPairs(value string)map[string]string{
+	pairs:=map[string]string{}
+	for _,entry:=range strings.Split(getEnv(value,""),","){
+		entry=strings.TrimSpace(entry)
+		if entry==""{
+			continue
+		}
+		k,v,ok:=strings.Cut(entry,":")
+		if !ok||k==""||v==""{
+			slog.Warn("ignoring malformed entry","variable",value)
+			continue
+		}
+		pairs[k]=v
+	}
+	return pairs
 }
\ No newline at end of file
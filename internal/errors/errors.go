@@ -1,21 +1,44 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldViolation describes one broken rule. Code is stable and meant for
+// clients; Message is for humans.
+type FieldViolation struct{
+	Field string `json:"field"`
+	Code string `json:"code"`
+	Message string `json:"message"`
+}
 
 type ValidationError struct{
-	Field interface{}
-	Message string
+	Violations []FieldViolation
 }
 
-func NewValidationError(field interface{} ,message string) *ValidationError{
-	return &ValidationError{
+func NewValidationError(field string ,message string) *ValidationError{
+	return NewFieldValidationError(FieldViolation{
 		Field: field,
+		Code: "invalid",
 		Message: message,
+	})
+}
+
+// NewFieldValidationError reports every violation at once so clients can fix
+// all of them in a single round trip.
+func NewFieldValidationError(violations ...FieldViolation) *ValidationError{
+	return &ValidationError{
+		Violations: violations,
 	}
 }
 
 func (v *ValidationError) Error()string{
-	return fmt.Sprintf("validation failed for field %s:,%s",v.Field,v.Message)
+	parts:=make([]string,0,len(v.Violations))
+	for _,f:=range v.Violations{
+		parts=append(parts,fmt.Sprintf("%s: %s",f.Field,f.Message))
+	}
+	return fmt.Sprintf("validation failed: %s",strings.Join(parts,"; "))
 }
 
 
@@ -25,7 +48,7 @@ type NotFoundError struct{
 }
 
 func (e *NotFoundError) Error()string{
-	return (fmt.Sprintf("%s not found with id %d",e.Resource,e.Val))
+	return (fmt.Sprintf("%s: %v",e.Resource,e.Val))
 }
 
 func NewNotFoundError(val interface{},resource string) *NotFoundError{
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Outbox is the storage the dispatcher reads pending events from.
+type Outbox interface {
+	// FetchPending returns undelivered events in ascending ID order,
+	// leaving out those given up on with MarkDead.
+	FetchPending(limit int) ([]Event, error)
+	MarkDelivered(id int64) error
+	MarkFailed(id int64, reason error) error
+	// MarkDead records the last failure of an event that will not be
+	// delivered anymore.
+	MarkDead(id int64, reason error) error
+}
+
+// Sink receives events. Deliver must be safe to call again with an event it
+// has already seen, since delivery is at-least-once.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, e Event) error
+}
+
+// Dispatcher polls the outbox and delivers each event to every sink. An event
+// is marked delivered only once all sinks accept it. When delivery fails, the
+// user's later events wait for the next poll so each user's events arrive in
+// order. After maxAttempts failed deliveries an event is given up on, so
+// it cannot hold back the user's later events, or fill the batch, forever.
+// Run a single dispatcher per database.
+type Dispatcher struct {
+	outbox      Outbox
+	sinks       []Sink
+	interval    time.Duration
+	maxAttempts int
+	batch       int
+}
+
+func NewDispatcher(outbox Outbox, interval time.Duration, maxAttempts int, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		outbox:      outbox,
+		sinks:       sinks,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		batch:       100,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("event dispatcher started", "interval", d.interval.String(), "sinks", len(d.sinks))
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.dispatchOnce(ctx)
+		select {
+		case <-ctx.Done():
+			slog.Info("event dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchOnce(ctx context.Context) {
+	pending, err := d.outbox.FetchPending(d.batch)
+	if err != nil {
+		slog.Error("unable to fetch pending events", "error", err)
+		return
+	}
+	blocked := map[int]bool{}
+	for _, e := range pending {
+		if blocked[e.UserID] {
+			continue
+		}
+		if err := d.deliver(ctx, e); err != nil {
+			if e.Attempts+1 >= d.maxAttempts {
+				slog.Error("giving up on event", "error", err, "event_id", e.ID, "event_type", e.Type, "user_id", e.UserID, "attempts", e.Attempts+1)
+				if markErr := d.outbox.MarkDead(e.ID, err); markErr != nil {
+					slog.Error("unable to record failed delivery", "error", markErr, "event_id", e.ID)
+					blocked[e.UserID] = true
+				}
+				continue
+			}
+			blocked[e.UserID] = true
+			if markErr := d.outbox.MarkFailed(e.ID, err); markErr != nil {
+				slog.Error("unable to record failed delivery", "error", markErr, "event_id", e.ID)
+			}
+			continue
+		}
+		if err := d.outbox.MarkDelivered(e.ID); err != nil {
+			// the event will be delivered again on the next poll
+			slog.Error("unable to mark event delivered", "error", err, "event_id", e.ID)
+			blocked[e.UserID] = true
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, e Event) error {
+	for _, s := range d.sinks {
+		if err := s.Deliver(ctx, e); err != nil {
+			slog.Warn("event delivery failed", "error", err, "sink", s.Name(), "event_id", e.ID, "event_type", e.Type)
+			return err
+		}
+	}
+	return nil
+}
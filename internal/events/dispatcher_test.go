@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+)
+
+// memOutbox keeps events in ID order with their delivery state.
+type memOutbox struct {
+	events    []Event
+	delivered map[int64]bool
+	dead      map[int64]bool
+}
+
+func newMemOutbox(events ...Event) *memOutbox {
+	return &memOutbox{events: events, delivered: map[int64]bool{}, dead: map[int64]bool{}}
+}
+
+func (o *memOutbox) FetchPending(limit int) ([]Event, error) {
+	var pending []Event
+	for _, e := range o.events {
+		if len(pending) < limit && !o.delivered[e.ID] && !o.dead[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (o *memOutbox) MarkDelivered(id int64) error {
+	o.delivered[id] = true
+	return nil
+}
+
+func (o *memOutbox) MarkFailed(id int64, reason error) error {
+	for i := range o.events {
+		if o.events[i].ID == id {
+			o.events[i].Attempts++
+		}
+	}
+	return nil
+}
+
+func (o *memOutbox) MarkDead(id int64, reason error) error {
+	o.dead[id] = true
+	return o.MarkFailed(id, reason)
+}
+
+// rejectingSink fails every event whose ID is in reject.
+type rejectingSink struct {
+	reject map[int64]bool
+	got    []int64
+}
+
+func (s *rejectingSink) Name() string { return "test" }
+
+func (s *rejectingSink) Deliver(ctx context.Context, e Event) error {
+	if s.reject[e.ID] {
+		return stderrors.New("rejected")
+	}
+	s.got = append(s.got, e.ID)
+	return nil
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	outbox := newMemOutbox(
+		Event{ID: 1, UserID: 1},
+		Event{ID: 2, UserID: 1},
+		Event{ID: 3, UserID: 2},
+	)
+	sink := &rejectingSink{reject: map[int64]bool{1: true}}
+	d := NewDispatcher(outbox, time.Second, 3, sink)
+
+	// event 1 holds back event 2 of the same user, but not user 2
+	d.dispatchOnce(context.Background())
+	d.dispatchOnce(context.Background())
+	if outbox.dead[1] || outbox.delivered[2] || !outbox.delivered[3] {
+		t.Fatalf("after 2 attempts: dead=%v delivered=%v", outbox.dead, outbox.delivered)
+	}
+
+	d.dispatchOnce(context.Background())
+	if !outbox.dead[1] {
+		t.Fatal("event 1 was not given up on after 3 attempts")
+	}
+	if !outbox.delivered[2] {
+		t.Error("event 2 is still held back by the dead event")
+	}
+	if pending, _ := outbox.FetchPending(10); len(pending) != 0 {
+		t.Errorf("pending after giving up: %+v", pending)
+	}
+}
+
+func TestDispatcherDeadEventsDoNotFillBatch(t *testing.T) {
+	var stuck []Event
+	for i := int64(1); i <= 5; i++ {
+		stuck = append(stuck, Event{ID: i, UserID: int(i)})
+	}
+	outbox := newMemOutbox(append(stuck, Event{ID: 6, UserID: 99})...)
+	reject := map[int64]bool{}
+	for _, e := range stuck {
+		reject[e.ID] = true
+	}
+	sink := &rejectingSink{reject: reject}
+	d := NewDispatcher(outbox, time.Second, 1, sink)
+	d.batch = 5
+
+	d.dispatchOnce(context.Background())
+	d.dispatchOnce(context.Background())
+	if !outbox.delivered[6] {
+		t.Errorf("event behind a full batch of failing events was never delivered")
+	}
+}
\ No newline at end of file
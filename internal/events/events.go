@@ -0,0 +1,90 @@
+// Package events defines the user lifecycle events written to the outbox and
+// the dispatcher that delivers them to sinks.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"user-management/internal/model"
+)
+
+const (
+	TypeUserCreated         = "user.created"
+	TypeUserUpdated         = "user.updated"
+	TypeUserEmailChanged    = "user.email_changed"
+	TypeUserPasswordChanged = "user.password_changed"
+	TypeUserDeactivated     = "user.deactivated"
+	TypeUserDeleted         = "user.deleted"
+)
+
+// Types lists every event type, for validating subscriptions.
+var Types = []string{
+	TypeUserCreated,
+	TypeUserUpdated,
+	TypeUserEmailChanged,
+	TypeUserPasswordChanged,
+	TypeUserDeactivated,
+	TypeUserDeleted,
+}
+
+// Event is the envelope stored in the outbox and handed to sinks. ID is
+// assigned by the outbox and increases with every event, so consumers can use
+// it to drop the duplicates that at-least-once delivery allows.
+type Event struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	UserID     int             `json:"user_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+	// Attempts counts the failed deliveries so far. It is set by the
+	// outbox and not sent to sinks.
+	Attempts int `json:"-"`
+}
+
+// Payload is implemented by every typed event body.
+type Payload interface {
+	EventType() string
+}
+
+type UserCreated struct {
+	User model.UserResponse `json:"user"`
+}
+
+type UserUpdated struct {
+	User model.UserResponse `json:"user"`
+}
+
+type UserEmailChanged struct {
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+}
+
+type UserPasswordChanged struct{}
+
+type UserDeactivated struct{}
+
+type UserDeleted struct {
+	UserID int `json:"user_id"`
+}
+
+func (UserCreated) EventType() string         { return TypeUserCreated }
+func (UserUpdated) EventType() string         { return TypeUserUpdated }
+func (UserEmailChanged) EventType() string    { return TypeUserEmailChanged }
+func (UserPasswordChanged) EventType() string { return TypeUserPasswordChanged }
+func (UserDeactivated) EventType() string     { return TypeUserDeactivated }
+func (UserDeleted) EventType() string         { return TypeUserDeleted }
+
+// New wraps payload in an envelope for userID.
+func New(userID int, payload Payload) (*Event, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode %s event %w", payload.EventType(), err)
+	}
+	return &Event{
+		Type:       payload.EventType(),
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+		Payload:    raw,
+	}, nil
+}
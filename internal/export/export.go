@@ -0,0 +1,224 @@
+// Package export writes users to CSV, NDJSON and Parquet one row at a time.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"user-management/internal/model"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Fields are the exportable columns, in default order. Secret columns such
+// as the password hash are never exported.
+var Fields = []string{"id", "username", "email", "name", "isactive"}
+
+// parquetRowGroup bounds how many rows the Parquet writer buffers.
+const parquetRowGroup = 10000
+
+// Writer encodes users. Close flushes anything buffered and must be called.
+type Writer interface {
+	Write(u model.User) error
+	Close() error
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// ParseFields validates a comma separated field list. An empty list selects
+// every field.
+func ParseFields(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Fields, nil
+	}
+	var selected []string
+	seen := map[string]bool{}
+	for _, f := range strings.Split(list, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !isField(f) {
+			return nil, fmt.Errorf("unknown field %q, use any of %s", f, strings.Join(Fields, ","))
+		}
+		if !seen[f] {
+			seen[f] = true
+			selected = append(selected, f)
+		}
+	}
+	return selected, nil
+}
+
+func NewWriter(format string, w io.Writer, fields []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, fields)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, fields), nil
+	case FormatParquet:
+		return newParquetWriter(w, fields), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q, use csv, ndjson or parquet", format)
+}
+
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns field of u as a string for CSV.
+func value(u model.User, field string) string {
+	switch field {
+	case "id":
+		return strconv.Itoa(u.ID)
+	case "username":
+		return u.Username
+	case "email":
+		return u.Email
+	case "name":
+		return u.Name
+	case "isactive":
+		return strconv.FormatBool(u.IsActive)
+	}
+	return ""
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	fields []string
+	record []string
+}
+
+func newCSVWriter(w io.Writer, fields []string) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fields); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw, fields: fields, record: make([]string, len(fields))}, nil
+}
+
+func (c *csvWriter) Write(u model.User) error {
+	for i, f := range c.fields {
+		c.record[i] = value(u, f)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w      *bufio.Writer
+	fields []string
+}
+
+func newNDJSONWriter(w io.Writer, fields []string) *ndjsonWriter {
+	return &ndjsonWriter{w: bufio.NewWriter(w), fields: fields}
+}
+
+func (n *ndjsonWriter) Write(u model.User) error {
+	// a map would lose the requested field order
+	n.w.WriteByte('{')
+	for i, f := range n.fields {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(f)
+		n.w.Write(key)
+		n.w.WriteByte(':')
+		var v any = value(u, f)
+		switch f {
+		case "id":
+			v = u.ID
+		case "isactive":
+			v = u.IsActive
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n.w.Write(b)
+	}
+	n.w.WriteString("}\n")
+	// flush per row so the client sees progress and nothing piles up
+	return n.w.Flush()
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+type parquetWriter struct {
+	w      *parquet.Writer
+	fields []string
+	row    parquet.Row
+}
+
+func newParquetWriter(w io.Writer, fields []string) *parquetWriter {
+	group := parquet.Group{}
+	for _, f := range fields {
+		switch f {
+		case "id":
+			group[f] = parquet.Int(64)
+		case "isactive":
+			group[f] = parquet.Leaf(parquet.BooleanType)
+		default:
+			group[f] = parquet.String()
+		}
+	}
+	schema := parquet.NewSchema("user", group)
+	// columns are stored in name order, whatever order was requested
+	var columns []string
+	for _, field := range schema.Fields() {
+		columns = append(columns, field.Name())
+	}
+	return &parquetWriter{
+		w:      parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(parquetRowGroup)),
+		fields: columns,
+		row:    make(parquet.Row, len(columns)),
+	}
+}
+
+func (p *parquetWriter) Write(u model.User) error {
+	for i, f := range p.fields {
+		var v parquet.Value
+		switch f {
+		case "id":
+			v = parquet.Int64Value(int64(u.ID))
+		case "isactive":
+			v = parquet.BooleanValue(u.IsActive)
+		default:
+			v = parquet.ByteArrayValue([]byte(value(u, f)))
+		}
+		p.row[i] = v.Level(0, 0, i)
+	}
+	_, err := p.w.WriteRows([]parquet.Row{p.row})
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
\ No newline at end of file
@@ -0,0 +1,80 @@
+// Package graph serves the GraphQL API over service.UserService. The
+// schema is in schema.graphql.
+package graph
+
+import (
+	_ "embed"
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"user-management/internal/auth"
+	"user-management/internal/errors"
+	"user-management/internal/problem"
+	"user-management/internal/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxDepth rejects queries nested deeper than the schema allows.
+const maxDepth = 5
+
+// NewHandler returns the /graphql handler. It expects
+// middleware.OptionalJWTMiddleware in front of it; resolvers decide which
+// operations need a token.
+func NewHandler(svc *service.UserService) http.Handler {
+	s := graphql.MustParseSchema(schema, &Resolver{service: svc}, graphql.MaxDepth(maxDepth))
+	h := &relay.Handler{Schema: s}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// one loader per request, so cached users never leak across callers
+		h.ServeHTTP(w, r.WithContext(withLoader(r.Context(), newUserLoader(svc))))
+	})
+}
+
+// Error is a resolver error with a machine-readable code in its extensions,
+// using the same codes as the problem responses of the REST API.
+type Error struct {
+	Message    string
+	Code       string
+	Violations []errors.FieldViolation
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	if len(e.Violations) > 0 {
+		ext["errors"] = e.Violations
+	}
+	return ext
+}
+
+var errUnauthenticated = &Error{Message: "authentication required", Code: problem.CodeUnauthorized}
+
+// toError maps service errors to GraphQL errors. Unexpected errors are
+// logged and hidden from the client.
+func toError(err error) error {
+	var (
+		validation *errors.ValidationError
+		notFound   *errors.NotFoundError
+		duplicate  *errors.DuplicateError
+	)
+	switch {
+	case stderrors.As(err, &validation):
+		return &Error{Message: "request failed validation", Code: problem.CodeValidationFailed, Violations: validation.Violations}
+	case stderrors.As(err, &notFound):
+		return &Error{Message: notFound.Error(), Code: problem.CodeNotFound}
+	case stderrors.As(err, &duplicate):
+		return &Error{Message: duplicate.Error(), Code: problem.CodeDuplicate}
+	case stderrors.Is(err, auth.ErrSessionRevoked):
+		return errUnauthenticated
+	default:
+		slog.Error("unhandled graphql error", "error", err)
+		return &Error{Message: "an unexpected error occurred", Code: problem.CodeInternal}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"context"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+	"user-management/internal/service"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+type loaderKey struct{}
+
+// userLoader batches the user lookups of one request into a single query
+// and caches them for the rest of the request.
+type userLoader = dataloader.Loader[int, *model.User]
+
+func newUserLoader(svc *service.UserService) *userLoader {
+	return dataloader.NewBatchedLoader(func(ctx context.Context, ids []int) []*dataloader.Result[*model.User] {
+		results := make([]*dataloader.Result[*model.User], len(ids))
+		users, err := svc.GetUsersByIDs(ids)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[*model.User]{Error: err}
+			}
+			return results
+		}
+		byID := make(map[int]*model.User, len(users))
+		for i := range users {
+			byID[users[i].ID] = &users[i]
+		}
+		// results must line up with ids
+		for i, id := range ids {
+			if u, ok := byID[id]; ok {
+				results[i] = &dataloader.Result[*model.User]{Data: u}
+			} else {
+				results[i] = &dataloader.Result[*model.User]{Error: errors.NewNotFoundError(id, "no user with that id")}
+			}
+		}
+		return results
+	})
+}
+
+func withLoader(ctx context.Context, l *userLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+func loaderFrom(ctx context.Context) *userLoader {
+	return ctx.Value(loaderKey{}).(*userLoader)
+}
\ No newline at end of file
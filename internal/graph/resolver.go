@@ -0,0 +1,281 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"strconv"
+	"user-management/internal/errors"
+	"user-management/internal/middleware"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+	"user-management/internal/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const maxPageSize = 100
+
+// Resolver is the root resolver. Like the REST API, every operation except
+// createUser needs a full access token, and any authenticated user may read
+// and change any user.
+type Resolver struct {
+	service *service.UserService
+}
+
+func (r *Resolver) Users(ctx context.Context, args struct {
+	First  int32
+	After  *string
+	Active *bool
+	Search *string
+}) (*userConnection, error) {
+	if _, err := authenticated(ctx); err != nil {
+		return nil, err
+	}
+	if args.First < 1 || args.First > maxPageSize {
+		return nil, &Error{Message: "first must be between 1 and 100", Code: problem.CodeValidationFailed}
+	}
+	filter := model.UserFilter{Active: args.Active}
+	if args.Search != nil {
+		filter.Search = *args.Search
+	}
+	if args.After != nil {
+		id, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		filter.AfterID = id
+	}
+	users, more, err := r.service.ListUsersPage(filter, int(args.First))
+	if err != nil {
+		return nil, toError(err)
+	}
+	// later user(id) lookups in the same request reuse these
+	loader := loaderFrom(ctx)
+	for i := range users {
+		loader.Prime(ctx, users[i].ID, &users[i])
+	}
+	return &userConnection{users: users, more: more}, nil
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	if _, err := authenticated(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		// no user can have it
+		return nil, nil
+	}
+	u, err := loaderFrom(ctx).Load(ctx, id)()
+	var notFound *errors.NotFoundError
+	if stderrors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &userResolver{u}, nil
+}
+
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	claims, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+	u, err := loaderFrom(ctx).Load(ctx, id)()
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &userResolver{u}, nil
+}
+
+type createUserInput struct {
+	Username string
+	Email    string
+	Password string
+	Name     *string
+}
+
+func (r *Resolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userResolver, error) {
+	user := model.CreateUserRequest{
+		Username: args.Input.Username,
+		Email:    args.Input.Email,
+		Password: args.Input.Password,
+		Name:     deref(args.Input.Name),
+	}.ToUser()
+	if err := r.service.CreateUser(&user); err != nil {
+		return nil, toError(err)
+	}
+	return &userResolver{&user}, nil
+}
+
+type updateUserInput struct {
+	Username string
+	Email    string
+	Name     *string
+}
+
+func (r *Resolver) UpdateUser(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateUserInput
+}) (*userResolver, error) {
+	if _, err := authenticated(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	user := model.UpdateUserRequest{
+		Username: args.Input.Username,
+		Email:    args.Input.Email,
+		Name:     deref(args.Input.Name),
+	}.ToUser()
+	if err := r.service.UpdateUser(id, user); err != nil {
+		return nil, toError(err)
+	}
+	loader := loaderFrom(ctx)
+	loader.Clear(ctx, id)
+	updated, err := loader.Load(ctx, id)()
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &userResolver{updated}, nil
+}
+
+func (r *Resolver) DeleteUser(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if _, err := authenticated(ctx); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.service.DeleteUser(id); err != nil {
+		return false, toError(err)
+	}
+	loaderFrom(ctx).Clear(ctx, id)
+	return true, nil
+}
+
+type userResolver struct {
+	u *model.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(r.u.ID))
+}
+
+func (r *userResolver) Username() string {
+	return r.u.Username
+}
+
+func (r *userResolver) Email() string {
+	return r.u.Email
+}
+
+func (r *userResolver) Name() *string {
+	if r.u.Name == "" {
+		return nil
+	}
+	return &r.u.Name
+}
+
+func (r *userResolver) Active() bool {
+	return r.u.IsActive
+}
+
+type userConnection struct {
+	users []model.User
+	more  bool
+}
+
+func (c *userConnection) Edges() []*userEdge {
+	edges := make([]*userEdge, len(c.users))
+	for i := range c.users {
+		edges[i] = &userEdge{&c.users[i]}
+	}
+	return edges
+}
+
+func (c *userConnection) PageInfo() *pageInfo {
+	info := &pageInfo{hasNext: c.more}
+	if len(c.users) > 0 {
+		cursor := encodeCursor(c.users[len(c.users)-1].ID)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type userEdge struct {
+	u *model.User
+}
+
+func (e *userEdge) Cursor() string {
+	return encodeCursor(e.u.ID)
+}
+
+func (e *userEdge) Node() *userResolver {
+	return &userResolver{e.u}
+}
+
+type pageInfo struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNext
+}
+
+func (p *pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+// authenticated returns the claims set by the auth middleware. Tokens for an
+// expired password are refused there, so only full access tokens get here.
+func authenticated(ctx context.Context) (*model.AccessClaims, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	return claims, nil
+}
+
+func parseID(id graphql.ID) (int, error) {
+	n, err := strconv.Atoi(string(id))
+	if err != nil || n <= 0 {
+		return 0, &Error{Message: "invalid id format", Code: problem.CodeInvalidID}
+	}
+	return n, nil
+}
+
+// Cursors are opaque to clients; they hold the id of the last user seen.
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("user:" + strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	invalid := &Error{Message: "invalid cursor", Code: problem.CodeValidationFailed}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) < 5 || string(raw[:5]) != "user:" {
+		return 0, invalid
+	}
+	id, err := strconv.Atoi(string(raw[5:]))
+	if err != nil {
+		return 0, invalid
+	}
+	return id, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
\ No newline at end of file
@@ -0,0 +1,99 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	usersv1 "user-management/api/users/v1"
+	"user-management/internal/auth"
+	"user-management/internal/config"
+	"user-management/internal/middleware"
+	"user-management/internal/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods can be called without a token, like their REST counterparts.
+var publicMethods = map[string]bool{
+	usersv1.UserService_CreateUser_FullMethodName:    true,
+	usersv1.UserService_Login_FullMethodName:         true,
+	usersv1.UserService_ValidateToken_FullMethodName: true,
+}
+
+// Authenticator is the gRPC equivalent of middleware.JWTMiddleware. It reads
+// the token from the "authorization" metadata and applies the same checks.
+type Authenticator struct {
+	cfg      *config.Config
+	sessions middleware.SessionValidator
+}
+
+func NewAuthenticator(cfg *config.Config, sessions middleware.SessionValidator) *Authenticator {
+	return &Authenticator{cfg: cfg, sessions: sessions}
+}
+
+func (a *Authenticator) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if publicMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate returns ctx with the caller's claims.
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid auth format, use: Bearer <token>")
+	}
+	claims, err := a.validate(token)
+	if err != nil {
+		slog.Warn("grpc call rejected", "error", err, "method", method)
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	if claims.Scope == model.ScopePasswordChange {
+		return nil, status.Error(codes.PermissionDenied, "password expired, change your password to continue")
+	}
+	return middleware.WithClaims(ctx, claims), nil
+}
+
+// validate checks the signature and expiry, then revocation and deactivation.
+func (a *Authenticator) validate(token string) (*model.AccessClaims, error) {
+	claims, err := auth.ValidateToken(token, a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.sessions.CheckSession(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
\ No newline at end of file
@@ -0,0 +1,170 @@
+// Package grpcserver exposes service.UserService over gRPC, as defined in
+// api/users/v1/users.proto.
+package grpcserver
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	usersv1 "user-management/api/users/v1"
+	"user-management/internal/auth"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+	"user-management/internal/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	usersv1.UnimplementedUserServiceServer
+	service *service.UserService
+	auth    *Authenticator
+}
+
+// New returns a gRPC server with the user service registered behind the
+// authenticator's interceptors.
+func New(service *service.UserService, authenticator *Authenticator) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.Unary),
+		grpc.ChainStreamInterceptor(authenticator.Stream),
+	)
+	usersv1.RegisterUserServiceServer(s, &Server{service: service, auth: authenticator})
+	return s
+}
+
+func (s *Server) GetUser(ctx context.Context, req *usersv1.GetUserRequest) (*usersv1.User, error) {
+	user, err := s.service.GetUser(int(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(user), nil
+}
+
+func (s *Server) ListUsers(req *usersv1.ListUsersRequest, stream grpc.ServerStreamingServer[usersv1.User]) error {
+	filter := model.UserFilter{Active: req.Active, Search: req.Query}
+	err := s.service.ExportUsers(filter, func(u model.User) error {
+		return stream.Send(toProto(&u))
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *usersv1.CreateUserRequest) (*usersv1.User, error) {
+	user := model.CreateUserRequest{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+		Name:     req.Name,
+	}.ToUser()
+	if err := s.service.CreateUser(&user); err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(&user), nil
+}
+
+func (s *Server) UpdateUser(ctx context.Context, req *usersv1.UpdateUserRequest) (*usersv1.User, error) {
+	user := model.UpdateUserRequest{
+		Username: req.Username,
+		Email:    req.Email,
+		Name:     req.Name,
+	}.ToUser()
+	if err := s.service.UpdateUser(int(req.Id), user); err != nil {
+		return nil, toStatus(err)
+	}
+	updated, err := s.service.GetUser(int(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(updated), nil
+}
+
+func (s *Server) DeleteUser(ctx context.Context, req *usersv1.DeleteUserRequest) (*usersv1.DeleteUserResponse, error) {
+	if err := s.service.DeleteUser(int(req.Id)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &usersv1.DeleteUserResponse{}, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *usersv1.LoginRequest) (*usersv1.LoginResponse, error) {
+	token, changeRequired, err := s.service.Login(req.Email, req.Password)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &usersv1.LoginResponse{Token: token, PasswordChangeRequired: changeRequired}, nil
+}
+
+// ValidateToken answers for any token, so invalid tokens are a normal
+// response with valid=false rather than an error.
+func (s *Server) ValidateToken(ctx context.Context, req *usersv1.ValidateTokenRequest) (*usersv1.ValidateTokenResponse, error) {
+	claims, err := s.auth.validate(req.Token)
+	if err != nil {
+		return &usersv1.ValidateTokenResponse{Valid: false}, nil
+	}
+	res := &usersv1.ValidateTokenResponse{
+		Valid:   true,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Scope:   claims.Scope,
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = timestamppb.New(claims.IssuedAt.Time)
+	}
+	if claims.ExpiresAt != nil {
+		res.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	return res, nil
+}
+
+func toProto(u *model.User) *usersv1.User {
+	return &usersv1.User{
+		Id:       int64(u.ID),
+		Username: u.Username,
+		Email:    u.Email,
+		Name:     u.Name,
+		Active:   u.IsActive,
+	}
+}
+
+// toStatus maps service errors to gRPC status codes the way problem.FromError
+// maps them to HTTP statuses. Field violations are attached as BadRequest
+// details.
+func toStatus(err error) error {
+	var (
+		validation *errors.ValidationError
+		notFound   *errors.NotFoundError
+		duplicate  *errors.DuplicateError
+	)
+	switch {
+	case stderrors.As(err, &validation):
+		st := status.New(codes.InvalidArgument, "request failed validation")
+		details := &errdetails.BadRequest{}
+		for _, v := range validation.Violations {
+			details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+				Reason:      v.Code,
+			})
+		}
+		if withDetails, err := st.WithDetails(details); err == nil {
+			st = withDetails
+		}
+		return st.Err()
+	case stderrors.As(err, &notFound):
+		return status.Error(codes.NotFound, notFound.Error())
+	case stderrors.As(err, &duplicate):
+		return status.Error(codes.AlreadyExists, duplicate.Error())
+	case stderrors.Is(err, auth.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case stderrors.Is(err, auth.ErrSessionRevoked):
+		return status.Error(codes.Unauthenticated, "invalid or expired token")
+	default:
+		slog.Error("unhandled grpc error", "error", err)
+		return status.Error(codes.Internal, "an unexpected error occurred")
+	}
+}
\ No newline at end of file
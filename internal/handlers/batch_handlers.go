@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+	"user-management/internal/service"
+)
+
+// BatchHandler runs POST /users:batch. The response is 200 when every
+// operation succeeded, 207 when a best-effort batch partly failed and 422
+// when an all-or-nothing batch was rolled back.
+func (h *UserHandler) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	results, err := h.service.Batch(req.Mode, req.Operations)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	response := model.BatchResponse{Mode: req.Mode, Results: make([]model.BatchItemResponse, len(results))}
+	if response.Mode == "" {
+		response.Mode = model.BatchAllOrNothing
+	}
+	for i, result := range results {
+		item := model.BatchItemResponse{Index: i, Op: result.Op}
+		if result.Err != nil {
+			item.Status, item.Error = batchError(r, result.Err)
+			response.Failed++
+		} else {
+			item.Status = batchSuccessStatus(result.Op)
+			if result.User != nil {
+				user := model.NewUserResponse(result.User)
+				item.User = &user
+			}
+			response.Succeeded++
+		}
+		response.Results[i] = item
+	}
+
+	status := http.StatusOK
+	switch {
+	case response.Failed > 0 && response.Mode == model.BatchAllOrNothing:
+		status = http.StatusUnprocessableEntity
+	case response.Failed > 0:
+		status = http.StatusMultiStatus
+	}
+	writeJSON(w, status, response)
+}
+
+func batchError(r *http.Request, err error) (int, *model.BatchError) {
+	if stderrors.Is(err, service.ErrBatchAborted) {
+		return http.StatusFailedDependency, &model.BatchError{Code: problem.CodeBatchAborted, Detail: err.Error()}
+	}
+	p := problem.FromError(r, err)
+	return p.Status, &model.BatchError{Code: p.Code, Detail: p.Detail, Errors: p.Errors}
+}
+
+func batchSuccessStatus(op string) int {
+	switch op {
+	case model.BatchCreate:
+		return http.StatusCreated
+	case model.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
\ No newline at end of file
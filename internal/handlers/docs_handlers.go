@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"user-management/internal/openapi"
+
+	"github.com/gorilla/mux"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// docsPage loads the embedded Swagger UI from /docs/ and points it at the
+// spec, so the docs work without network access.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>User Management API</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// DocsHandler serves the OpenAPI document and a Swagger UI for it.
+type DocsHandler struct {
+	spec   []byte
+	assets http.Handler
+}
+
+func NewDocsHandler(doc *openapi.Document) (*DocsHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{
+		spec:   spec,
+		assets: http.StripPrefix("/docs/", http.FileServer(http.FS(swaggerFiles.FS))),
+	}, nil
+}
+
+func (h *DocsHandler) SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+func (h *DocsHandler) UIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(docsPage))
+}
+
+// AssetHandler serves the Swagger UI files. The stock index.html is not
+// used, since it loads the petstore example.
+func (h *DocsHandler) AssetHandler(w http.ResponseWriter, r *http.Request) {
+	if mux.Vars(r)["asset"] == "index.html" {
+		http.NotFound(w, r)
+		return
+	}
+	h.assets.ServeHTTP(w, r)
+}
\ No newline at end of file
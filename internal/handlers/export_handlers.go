@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"user-management/internal/export"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+)
+
+// ExportHandler streams the users matching the list filters in the requested
+// format. Rows are written as they are read from the database, so the export
+// cannot fail with a problem response once it has started; a broken export
+// ends early and the client sees a truncated body.
+func (h *UserHandler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseUserFilter(w, r)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	fields, err := export.ParseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeValidationFailed, err.Error())
+		return
+	}
+	if format != export.FormatCSV && format != export.FormatNDJSON && format != export.FormatParquet {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "format must be csv, ndjson or parquet")
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="users.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+	writer, err := export.NewWriter(format, w, fields)
+	if err != nil {
+		slog.Error("unable to start export", "error", err, "format", format)
+		return
+	}
+	count := 0
+	err = h.service.ExportUsers(filter, func(u model.User) error {
+		count++
+		return writer.Write(u)
+	})
+	if err != nil {
+		slog.Error("export aborted", "error", err, "format", format, "rows", count)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		slog.Error("unable to finish export", "error", err, "format", format)
+		return
+	}
+	slog.Info("users exported", "format", format, "rows", count)
+}
+
+// parseUserFilter reads the filters shared by the list and export endpoints:
+// active=true|false and q=<text>.
+func parseUserFilter(w http.ResponseWriter, r *http.Request) (model.UserFilter, bool) {
+	query := r.URL.Query()
+	filter := model.UserFilter{Search: query.Get("q")}
+	if v := query.Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "active must be true or false")
+			return filter, false
+		}
+		filter.Active = &active
+	}
+	return filter, true
+}
\ No newline at end of file
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"user-management/internal/config"
+	"user-management/internal/imports"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+	"user-management/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ColumnMappingHeader renames CSV columns to user fields, e.g.
+// "Work Email=email,Login=username".
+const ColumnMappingHeader = "X-Import-Columns"
+
+type ImportHandler struct {
+	service *service.UserService
+	jobs    *imports.Jobs
+	cfg     config.ImportConfig
+}
+
+func NewImportHandler(service *service.UserService, jobs *imports.Jobs, cfg config.ImportConfig) *ImportHandler {
+	return &ImportHandler{service: service, jobs: jobs, cfg: cfg}
+}
+
+// ImportHandler reads a CSV or NDJSON upload. Small uploads are imported
+// before responding with the finished job; larger ones are saved to a
+// temporary file and imported in the background, answering 202 with a
+// Location to poll.
+func (h *ImportHandler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := importFormat(w, r)
+	if !ok {
+		return
+	}
+	opts, ok := importOptions(w, r)
+	if !ok {
+		return
+	}
+	mapping, err := imports.ParseColumnMapping(r.Header.Get(ColumnMappingHeader))
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidImport, err.Error())
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, h.cfg.MaxSize)
+	job := h.jobs.Create(format, opts.DryRun, opts.OnDuplicate)
+
+	if r.ContentLength >= 0 && r.ContentLength <= h.cfg.AsyncThreshold {
+		rows, err := newImportReader(format, body, mapping)
+		if err != nil {
+			job.Finish(err)
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidImport, err.Error())
+			return
+		}
+		if err := h.service.ImportUsers(rows, opts, job); err != nil {
+			slog.Error("import failed", "error", err, "import_id", job.Snapshot().ID)
+		}
+		writeJSON(w, http.StatusOK, job.Snapshot())
+		return
+	}
+
+	// the request body is gone once the handler returns
+	file, err := os.CreateTemp("", "user-import-*")
+	if err != nil {
+		slog.Error("unable to create import spool file", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "an unexpected error occurred")
+		return
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		job.Finish(err)
+		problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, "upload exceeds the import size limit or was interrupted")
+		return
+	}
+	rows, err := newImportReader(format, rewind(file), mapping)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		job.Finish(err)
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidImport, err.Error())
+		return
+	}
+	go func() {
+		defer os.Remove(file.Name())
+		defer file.Close()
+		if err := h.service.ImportUsers(rows, opts, job); err != nil {
+			slog.Error("import failed", "error", err, "import_id", job.Snapshot().ID)
+		}
+	}()
+	snapshot := job.Snapshot()
+	w.Header().Set("Location", "/users/import/jobs/"+snapshot.ID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// GetJobHandler reports the progress of an import.
+func (h *ImportHandler) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(mux.Vars(r)["id"])
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job.Snapshot())
+}
+
+// ReportHandler downloads the rows that failed so far as CSV.
+func (h *ImportHandler) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(mux.Vars(r)["id"])
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="import-`+job.Snapshot().ID+`-errors.csv"`)
+	w.WriteHeader(http.StatusOK)
+	if err := job.WriteReport(w); err != nil {
+		slog.Error("unable to write import report", "error", err)
+	}
+}
+
+func importFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return imports.FormatCSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return imports.FormatNDJSON, true
+	}
+	problem.Write(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "send text/csv or application/x-ndjson")
+	return "", false
+}
+
+func importOptions(w http.ResponseWriter, r *http.Request) (service.ImportOptions, bool) {
+	query := r.URL.Query()
+	opts := service.ImportOptions{OnDuplicate: model.OnDuplicateSkip}
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "dry_run must be true or false")
+			return opts, false
+		}
+		opts.DryRun = dryRun
+	}
+	switch v := query.Get("on_duplicate"); v {
+	case "", model.OnDuplicateSkip:
+	case model.OnDuplicateUpsert:
+		opts.OnDuplicate = v
+	default:
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "on_duplicate must be skip or upsert")
+		return opts, false
+	}
+	return opts, true
+}
+
+func newImportReader(format string, r io.Reader, mapping map[string]string) (imports.Reader, error) {
+	if format == imports.FormatCSV {
+		return imports.NewCSVReader(r, mapping)
+	}
+	return imports.NewNDJSONReader(r), nil
+}
+
+func rewind(file *os.File) *os.File {
+	file.Seek(0, io.SeekStart)
+	return file
+}
\ No newline at end of file
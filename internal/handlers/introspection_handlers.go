@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"user-management/internal/service"
+	"user-management/pkg/introspect"
+)
+
+// IntrospectionHandler implements RFC 7662 token introspection for other
+// services. Callers authenticate with HTTP Basic client credentials.
+type IntrospectionHandler struct {
+	service *service.UserService
+	clients map[string]string
+}
+
+func NewIntrospectionHandler(service *service.UserService, clients map[string]string) *IntrospectionHandler {
+	return &IntrospectionHandler{service: service, clients: clients}
+}
+
+// IntrospectHandler answers for the token form parameter. Every token that
+// would be rejected by the auth middleware is reported as inactive, without
+// saying why. The service has no roles yet, so roles is never set.
+func (h *IntrospectionHandler) IntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="introspection"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	token := r.PostFormValue("token")
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	claims, err := h.service.IntrospectToken(token)
+	if err != nil {
+		slog.Info("introspected inactive token", "client_id", client, "reason", err)
+		writeJSON(w, http.StatusOK, introspect.Response{Active: false})
+		return
+	}
+	res := introspect.Response{
+		Active:    true,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		Scope:     claims.Scope,
+		TokenType: "Bearer",
+	}
+	if claims.ExpiresAt != nil {
+		res.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Unix()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// authenticate checks the Basic credentials, which RFC 6749 form-encodes,
+// and returns the client id.
+func (h *IntrospectionHandler) authenticate(r *http.Request) (string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	if decoded, err := url.QueryUnescape(id); err == nil {
+		id = decoded
+	}
+	if decoded, err := url.QueryUnescape(secret); err == nil {
+		secret = decoded
+	}
+	expected, ok := h.clients[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		slog.Warn("introspection client rejected", "client_id", id, "remote_addr", r.RemoteAddr)
+		return "", false
+	}
+	return id, true
+}
\ No newline at end of file
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"user-management/internal/middleware"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+)
+
+// currentUserID resolves the user id from the token subject. It writes a 401
+// and returns false when the request carries no usable claims.
+func currentUserID(w http.ResponseWriter,r *http.Request)(int,bool){
+	claims,ok:=middleware.ClaimsFromContext(r.Context())
+	if !ok{
+		problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"authorization required")
+		return 0,false
+	}
+	id,err:=strconv.Atoi(claims.Subject)
+	if err!=nil{
+		problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"invalid token subject")
+		return 0,false
+	}
+	return id,true
+}
+
+func (h *UserHandler) GetMeHandler(w http.ResponseWriter,r *http.Request){
+	id,ok:=currentUserID(w,r)
+	if !ok{
+		return
+	}
+	user,err:=h.service.GetUser(id)
+	if err!=nil{
+		h.handleServiceError(w,r,err)
+		return
+	}
+	h.writeUser(w,user)
+}
+
+func (h *UserHandler) PatchMeHandler(w http.ResponseWriter,r *http.Request){
+	id,ok:=currentUserID(w,r)
+	if !ok{
+		return
+	}
+	patch,ok:=h.decodePatch(w,r)
+	if !ok{
+		return
+	}
+	if err:=h.service.PatchUser(id,patch);err!=nil{
+		h.handleServiceError(w,r,err)
+		return
+	}
+	updatedUser,err:=h.service.GetUser(id)
+	if err!=nil{
+		h.handleServiceError(w,r,err)
+		return
+	}
+	h.writeUser(w,updatedUser)
+}
+
+func (h *UserHandler) DeleteMeHandler(w http.ResponseWriter,r *http.Request){
+	id,ok:=currentUserID(w,r)
+	if !ok{
+		return
+	}
+	if err:=h.service.DeleteUser(id);err!=nil{
+		h.handleServiceError(w,r,err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// SessionHandler describes the token used for the request.
+func (h *UserHandler) SessionHandler(w http.ResponseWriter,r *http.Request){
+	claims,ok:=middleware.ClaimsFromContext(r.Context())
+	if !ok{
+		problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"authorization required")
+		return
+	}
+	response:=model.SessionResponse{
+		Subject: claims.Subject,
+		Email: claims.Email,
+		Scope: claims.Scope,
+	}
+	if claims.IssuedAt!=nil{
+		response.IssuedAt=claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt!=nil{
+		response.ExpiresAt=claims.ExpiresAt.Time
+		response.ExpiresIn=int64(time.Until(claims.ExpiresAt.Time).Seconds())
+	}
+	w.Header().Set("Content-Type","application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
\ No newline at end of file
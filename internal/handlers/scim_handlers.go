@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+	"user-management/internal/scim"
+	"user-management/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// SCIMHandler serves the SCIM 2.0 User endpoints for identity providers.
+// Errors use the SCIM error body instead of problem details, as the
+// protocol requires.
+type SCIMHandler struct {
+	service *service.UserService
+	token   []byte
+}
+
+func NewSCIMHandler(service *service.UserService, token string) *SCIMHandler {
+	return &SCIMHandler{service: service, token: []byte(token)}
+}
+
+// Authenticate admits requests carrying the provisioning client's bearer
+// token. User JWTs are not accepted here.
+func (h *SCIMHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), h.token) != 1 {
+			slog.Warn("scim request rejected", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeSCIMError(w, http.StatusUnauthorized, "", "invalid or missing bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListHandler supports filter, startIndex and count. The filter is applied
+// in memory over all users, which is fine for the directory sizes
+// provisioning is used with.
+func (h *SCIMHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter scim.Filter
+	if s := query.Get("filter"); s != "" {
+		f, err := scim.ParseFilter(s)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		filter = f
+	}
+	startIndex, count := 1, 100
+	if v := query.Get("startIndex"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+			return
+		}
+		// values below 1 are interpreted as 1
+		startIndex = max(n, 1)
+	}
+	if v := query.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", "count must be an integer")
+			return
+		}
+		count = min(max(n, 0), scim.MaxResults)
+	}
+
+	users, err := h.service.GetAllUsers(model.UserFilter{})
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	var matched []scim.User
+	for i := range users {
+		res := scim.FromUser(&users[i])
+		if filter == nil || filter.Matches(res) {
+			matched = append(matched, res)
+		}
+	}
+	page := []scim.User{}
+	if start := startIndex - 1; start < len(matched) {
+		page = matched[start:min(start+count, len(matched))]
+	}
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(page, startIndex, len(matched)))
+}
+
+func (h *SCIMHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.service.GetUser(id)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, scim.FromUser(user))
+}
+
+// CreateHandler creates a user from the resource. Users created without a
+// password get a random one, and active defaults to true.
+func (h *SCIMHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	var res scim.User
+	if !decodeSCIM(w, r, &res) {
+		return
+	}
+	user := res.ToUser()
+	if err := h.service.ProvisionUser(&user, res.Active == nil || *res.Active); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	created := scim.FromUser(&user)
+	w.Header().Set("Location", created.Meta.Location)
+	writeSCIM(w, http.StatusCreated, created)
+}
+
+// ReplaceHandler is PUT: the resource becomes the new state of the user.
+func (h *SCIMHandler) ReplaceHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(w, r)
+	if !ok {
+		return
+	}
+	var res scim.User
+	if !decodeSCIM(w, r, &res) {
+		return
+	}
+	h.replace(w, r, id, res)
+}
+
+// PatchHandler applies the PatchOp operations to the current resource and
+// stores the result like a replace.
+func (h *SCIMHandler) PatchHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(w, r)
+	if !ok {
+		return
+	}
+	var req scim.PatchRequest
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	user, err := h.service.GetUser(id)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	res := scim.FromUser(user)
+	if err := scim.Apply(&res, req.Operations); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	h.replace(w, r, id, res)
+}
+
+func (h *SCIMHandler) replace(w http.ResponseWriter, r *http.Request, id int, res scim.User) {
+	if res.Password != "" {
+		writeSCIMError(w, http.StatusBadRequest, "mutability", "password can only be set when the user is created")
+		return
+	}
+	if err := h.service.ReplaceUser(id, res.ToUser(), res.Active); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	user, err := h.service.GetUser(id)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, scim.FromUser(user))
+}
+
+func (h *SCIMHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := scimID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteUser(id); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) ServiceProviderConfigHandler(w http.ResponseWriter, r *http.Request) {
+	writeSCIM(w, http.StatusOK, scim.NewServiceProviderConfig())
+}
+
+func (h *SCIMHandler) ResourceTypesHandler(w http.ResponseWriter, r *http.Request) {
+	types := scim.NewResourceTypes()
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(types, 1, len(types)))
+}
+
+func (h *SCIMHandler) SchemasHandler(w http.ResponseWriter, r *http.Request) {
+	schemas := scim.NewSchemas()
+	writeSCIM(w, http.StatusOK, scim.NewListResponse(schemas, 1, len(schemas)))
+}
+
+func (h *SCIMHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		validation *errors.ValidationError
+		notFound   *errors.NotFoundError
+		duplicate  *errors.DuplicateError
+	)
+	switch {
+	case stderrors.As(err, &validation):
+		details := make([]string, len(validation.Violations))
+		for i, v := range validation.Violations {
+			details[i] = v.Field + ": " + v.Message
+		}
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", strings.Join(details, "; "))
+	case stderrors.As(err, &notFound):
+		writeSCIMError(w, http.StatusNotFound, "", "resource not found")
+	case stderrors.As(err, &duplicate):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", duplicate.Error())
+	default:
+		slog.Error("unhandled scim error", "error", err, "path", r.URL.Path)
+		writeSCIMError(w, http.StatusInternalServerError, "", "an unexpected error occurred")
+	}
+}
+
+// scimID reads the id path variable. Ids that cannot exist are reported as
+// not found rather than as a bad request.
+func scimID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		writeSCIMError(w, http.StatusNotFound, "", "resource not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeSCIM is lenient about unknown attributes, since identity providers
+// routinely send extension schemas the service does not store.
+func decodeSCIM(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "request body is not a valid SCIM resource")
+		return false
+	}
+	return true
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeSCIM(w, status, scim.NewError(status, scimType, detail))
+}
\ No newline at end of file
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"user-management/internal/events"
+	"user-management/internal/stream"
+)
+
+// EventStreamHandler serves user events as Server-Sent Events.
+type EventStreamHandler struct {
+	broker    *stream.Broker
+	heartbeat time.Duration
+}
+
+func NewEventStreamHandler(broker *stream.Broker, heartbeat time.Duration) *EventStreamHandler {
+	return &EventStreamHandler{broker: broker, heartbeat: heartbeat}
+}
+
+// StreamHandler keeps the connection open and writes one SSE message per
+// event, with the outbox event id as the SSE id so reconnecting clients can
+// resume through Last-Event-ID. When the requested position is no longer
+// buffered a "reset" event is sent first, telling the client to reload.
+func (h *EventStreamHandler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	var lastEventID int64
+	if v := strings.TrimSpace(r.Header.Get("Last-Event-ID")); v != "" {
+		lastEventID, _ = strconv.ParseInt(v, 10, 64)
+	}
+	sub, replay, complete := h.broker.Subscribe(lastEventID)
+	defer h.broker.Cancel(sub)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range replay {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		slog.Error("event stream does not support flushing", "error", err)
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				// dropped for falling behind; the client reconnects and resumes
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		slog.Error("unable to encode streamed event", "error", err, "event_id", e.ID)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
\ No newline at end of file
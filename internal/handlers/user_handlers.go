@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"user-management/internal/errors"
 	"user-management/internal/model"
+	"user-management/internal/problem"
 	"user-management/internal/service"
 
 	"github.com/gorilla/mux"
@@ -14,53 +17,57 @@ import (
 
 type UserHandler struct {
 	service *service.UserService
+	// v2 selects the user shapes of the /v2 routes, see versions.go.
+	v2 bool
 }
 
 func NewUserHandler(service *service.UserService) *UserHandler {
 	return &UserHandler{service: service}
 }
+
+// V2 returns a handler for the /v2 routes, which take and return given and
+// family names instead of name.
+func (h *UserHandler) V2() *UserHandler {
+	return &UserHandler{service: h.service, v2: true}
+}
 func (h *UserHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := h.service.GetAllUsers()
+	filter, ok := parseUserFilter(w, r)
+	if !ok {
+		return
+	}
+	users, err := h.service.GetAllUsers(filter)
 	if err != nil {
-		http.Error(w, "unable to retrieve users", http.StatusInternalServerError)
+		h.handleServiceError(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(users)
+	h.writeUsers(w,users)
 }
 func (h *UserHandler) GetByIDHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idString := vars["id"]
 	id, err := strconv.Atoi(idString)
 	if err != nil {
-		http.Error(w, "invalid id format", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidID, "invalid id format")
 		return
 	}
 	user, err := h.service.GetUser(id)
 	if err!=nil{
-		h.handleServiceError(w,err)
+		h.handleServiceError(w,r,err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user)
+	h.writeUser(w,user)
 }
 func (h *UserHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	var user model.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w,"invalid json ",http.StatusBadRequest)
+	user,ok:=h.decodeCreate(w,r)
+	if !ok{
 		return
 	}
-	err=h.service.CreateUser(&user)
+	err:=h.service.CreateUser(&user)
 	if err!=nil{
-		h.handleServiceError(w,err)
+		h.handleServiceError(w,r,err)
 		return
 	}
-	w.Header().Set("Content-Type","application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user)
+	h.writeUser(w,&user)
 }
 
 func (h *UserHandler) UpdateHandler(w http.ResponseWriter,r *http.Request){
@@ -68,13 +75,11 @@ func (h *UserHandler) UpdateHandler(w http.ResponseWriter,r *http.Request){
 	id,err:=strconv.Atoi(vars["id"])
 	// fmt.Println("update handler called",id)
 	if err!=nil{
-		http.Error(w,"invalid id format ",http.StatusBadRequest)
+		problem.Write(w,r,http.StatusBadRequest,problem.CodeInvalidID,"invalid id format")
 		return
 	}
-	var user model.User
-	err=json.NewDecoder(r.Body).Decode(&user)
-	if err!=nil{
-		http.Error(w,"invalid json",http.StatusBadRequest)
+	user,ok:=h.decodeUpdate(w,r)
+	if !ok{
 		return
 	}
 	// fmt.Println("data to update:",user)
@@ -82,31 +87,29 @@ func (h *UserHandler) UpdateHandler(w http.ResponseWriter,r *http.Request){
 	err=h.service.UpdateUser(id,user)
 	if err!=nil{
 		fmt.Println("encountered error while updating",err)
-		h.handleServiceError(w,err)
+		h.handleServiceError(w,r,err)
 		return
 	}
 	updatedUser, err := h.service.GetUser(id)
 	// fmt.Println("updated user:",updatedUser)
     if err != nil {
-        h.handleServiceError(w, err)
+        h.handleServiceError(w, r, err)
         return
     }
-		w.Header().Set("Content-Type","application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedUser)
+	h.writeUser(w,updatedUser)
 }
 
 func (h *UserHandler) DeleteHandler(w http.ResponseWriter,r *http.Request){
 	vars:=mux.Vars(r)
 	id,err:=strconv.Atoi(vars["id"])
 	if err!=nil{
-		http.Error(w,"invalid id format ",http.StatusBadRequest)
+		problem.Write(w,r,http.StatusBadRequest,problem.CodeInvalidID,"invalid id format")
 		return
 	}
 	
 	err=h.service.DeleteUser(id)
 	if err!=nil{
-		h.handleServiceError(w,err)
+		h.handleServiceError(w,r,err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -115,33 +118,80 @@ func (h *UserHandler) DeleteHandler(w http.ResponseWriter,r *http.Request){
 
 func(h *UserHandler)LoginHandler(w http.ResponseWriter,r *http.Request){
 	var LoginRequest model.LoginRequest
-	err:=json.NewDecoder(r.Body).Decode(&LoginRequest)
-	if err!=nil{
-		http.Error(w,`{"error":"Invalid json format"}`,http.StatusBadRequest)
+	if !decodeJSON(w,r,&LoginRequest){
 		return
 	}
-	token,err:=h.service.Login(LoginRequest.Email,LoginRequest.Password)
+	token,changeRequired,err:=h.service.Login(LoginRequest.Email,LoginRequest.Password)
 	if err!=nil{
-		h.handleServiceError(w,err)
+		h.handleServiceError(w,r,err)
 		return
 	}
 	response:=model.LoginResponse{
 		Token: token,
 		Message: "Login successful",
 	}
+	if changeRequired{
+		response.Message="Password expired. Use this token to change your password"
+		response.PasswordChangeRequired=true
+	}
 	w.Header().Set("Content-Type","application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
-func (h *UserHandler) handleServiceError(w http.ResponseWriter,err error){
-	switch e:=err.(type){
-	case *errors.ValidationError:
-		http.Error(w,e.Error(),http.StatusBadRequest)
-	case *errors.NotFoundError:
-		http.Error(w,e.Error(),http.StatusNotFound)
-	case *errors.DuplicateError:
-		http.Error(w,e.Error(),http.StatusConflict)
+// ChangePasswordHandler changes the password of the authenticated user and
+// returns a new token, since all earlier tokens are revoked.
+func (h *UserHandler) ChangePasswordHandler(w http.ResponseWriter,r *http.Request){
+	id,ok:=currentUserID(w,r)
+	if !ok{
+		return
+	}
+	var req model.ChangePasswordRequest
+	if !decodeJSON(w,r,&req){
+		return
+	}
+	token,err:=h.service.ChangePassword(id,req.CurrentPassword,req.NewPassword)
+	if err!=nil{
+		h.handleServiceError(w,r,err)
+		return
+	}
+	w.Header().Set("Content-Type","application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ChangePasswordResponse{
+		Token: token,
+		Message: "Password changed. Other sessions have been signed out",
+	})
+}
+func (h *UserHandler) handleServiceError(w http.ResponseWriter,r *http.Request,err error){
+	problem.WriteError(w,r,h.versionError(err))
+}
+
+// decodeJSON decodes the request body into dst, rejecting unknown fields.
+// Type mismatches and unknown fields are reported per field; it writes the
+// problem response itself and returns false on failure.
+func decodeJSON(w http.ResponseWriter,r *http.Request,dst interface{})bool{
+	dec:=json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err:=dec.Decode(dst)
+	if err==nil{
+		return true
+	}
+	var typeErr *json.UnmarshalTypeError
+	switch{
+	case stderrors.As(err,&typeErr):
+		problem.WriteError(w,r,errors.NewFieldValidationError(errors.FieldViolation{
+			Field: typeErr.Field,
+			Code: "invalid_type",
+			Message: fmt.Sprintf("must be of type %s",typeErr.Type),
+		}))
+	case strings.HasPrefix(err.Error(),"json: unknown field "):
+		field:=strings.Trim(strings.TrimPrefix(err.Error(),"json: unknown field "),`"`)
+		problem.WriteError(w,r,errors.NewFieldValidationError(errors.FieldViolation{
+			Field: field,
+			Code: "unknown_field",
+			Message: "is not a recognized field",
+		}))
 	default:
-		http.Error(w,fmt.Sprintf("unknown error %w",err),http.StatusExpectationFailed)
+		problem.Write(w,r,http.StatusBadRequest,problem.CodeInvalidJSON,"invalid json")
 	}
+	return false
 }
\ No newline at end of file
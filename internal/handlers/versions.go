@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+)
+
+// The user routes are mounted under /v1 and /v2. Both trees share the
+// handlers; these helpers pick the request and response shapes of the
+// handler's version. Batch, import, export and events keep the v1 user
+// shape in both trees.
+
+func (h *UserHandler) decodeCreate(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	if h.v2 {
+		var req model.CreateUserRequestV2
+		if !decodeJSON(w, r, &req) {
+			return model.User{}, false
+		}
+		return req.ToUser(), true
+	}
+	var req model.CreateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return model.User{}, false
+	}
+	return req.ToUser(), true
+}
+
+func (h *UserHandler) decodeUpdate(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	if h.v2 {
+		var req model.UpdateUserRequestV2
+		if !decodeJSON(w, r, &req) {
+			return model.User{}, false
+		}
+		return req.ToUser(), true
+	}
+	var req model.UpdateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return model.User{}, false
+	}
+	return req.ToUser(), true
+}
+
+func (h *UserHandler) decodePatch(w http.ResponseWriter, r *http.Request) (model.UserPatch, bool) {
+	if h.v2 {
+		var patch model.UserPatchV2
+		if !decodeJSON(w, r, &patch) {
+			return model.UserPatch{}, false
+		}
+		return patch.ToPatch(), true
+	}
+	var patch model.UserPatch
+	if !decodeJSON(w, r, &patch) {
+		return model.UserPatch{}, false
+	}
+	return patch, true
+}
+
+func (h *UserHandler) writeUser(w http.ResponseWriter, user *model.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if h.v2 {
+		json.NewEncoder(w).Encode(model.NewUserResponseV2(user))
+		return
+	}
+	json.NewEncoder(w).Encode(model.NewUserResponse(user))
+}
+
+func (h *UserHandler) writeUsers(w http.ResponseWriter, users []model.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if h.v2 {
+		json.NewEncoder(w).Encode(model.NewUserResponsesV2(users))
+		return
+	}
+	json.NewEncoder(w).Encode(model.NewUserResponses(users))
+}
+
+// versionError rewrites violations of name, which v2 clients never send:
+// it is joined from given_name and family_name, so only their combined
+// length can break its limit.
+func (h *UserHandler) versionError(err error) error {
+	var validationErr *errors.ValidationError
+	if !h.v2 || !stderrors.As(err, &validationErr) {
+		return err
+	}
+	violations := make([]errors.FieldViolation, len(validationErr.Violations))
+	for i, v := range validationErr.Violations {
+		if v.Field == "name" {
+			v.Field = "given_name"
+			v.Message = "together with family_name " + v.Message
+		}
+		violations[i] = v
+	}
+	return errors.NewFieldValidationError(violations...)
+}
\ No newline at end of file
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+	"user-management/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// WebhookHandler serves the /webhooks API. Subscriptions receive every
+// user's events, so they are managed by operators with a static token
+// rather than by user tokens.
+type WebhookHandler struct {
+	service *service.WebhookService
+	token   []byte
+}
+
+func NewWebhookHandler(service *service.WebhookService, token string) *WebhookHandler {
+	return &WebhookHandler{service: service, token: []byte(token)}
+}
+
+// Authenticate requires the operator token as a bearer token.
+func (h *WebhookHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || len(h.token) == 0 || subtle.ConstantTimeCompare([]byte(token), h.token) != 1 {
+			slog.Warn("webhook admin request rejected", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="webhooks"`)
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "invalid or missing operator token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *WebhookHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.service.ListWebhooks()
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	responses := make([]model.WebhookResponse, 0, len(hooks))
+	for i := range hooks {
+		responses = append(responses, model.NewWebhookResponse(&hooks[i]))
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *WebhookHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateWebhookRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	hook, err := h.service.CreateWebhook(req)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	// the secret is only ever shown here
+	response := model.NewWebhookResponse(hook)
+	response.Secret = hook.Secret
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	hook, err := h.service.GetWebhook(id)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.NewWebhookResponse(hook))
+}
+
+func (h *WebhookHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	var req model.UpdateWebhookRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	hook, err := h.service.UpdateWebhook(id, req)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.NewWebhookResponse(hook))
+}
+
+func (h *WebhookHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteWebhook(id); err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveries, err := h.service.ListDeliveries(id)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []model.WebhookDelivery{}
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) GetDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, ok := deliveryPath(w, r)
+	if !ok {
+		return
+	}
+	delivery, err := h.service.GetDelivery(id, deliveryID)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// RedeliverHandler queues the delivery again; the deliverer sends it
+// asynchronously, so the response is 202.
+func (h *WebhookHandler) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, ok := deliveryPath(w, r)
+	if !ok {
+		return
+	}
+	delivery, err := h.service.Redeliver(id, deliveryID)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func deliveryPath(w http.ResponseWriter, r *http.Request) (int, int64, bool) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return 0, 0, false
+	}
+	deliveryID, err := strconv.ParseInt(mux.Vars(r)["deliveryID"], 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidID, "invalid delivery id format")
+		return 0, 0, false
+	}
+	return id, deliveryID, true
+}
+
+// pathInt parses an integer path variable, writing a 400 when it is malformed.
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	n, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidID, "invalid id format")
+		return 0, false
+	}
+	return n, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,174 @@
+package imports
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	stderrors "errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+)
+
+// Jobs keeps import jobs and their error reports in memory. Finished jobs
+// are forgotten after the retention period, and a restart loses them all.
+type Jobs struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	retention time.Duration
+}
+
+// Job is a running or finished import. Its methods are safe for concurrent use.
+type Job struct {
+	mu     sync.Mutex
+	state  model.ImportJob
+	errors []model.ImportRowError
+}
+
+func NewJobs(retention time.Duration) *Jobs {
+	return &Jobs{jobs: map[string]*Job{}, retention: retention}
+}
+
+// Create registers a pending job.
+func (j *Jobs) Create(format string, dryRun bool, onDuplicate string) *Job {
+	var b [16]byte
+	rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+	job := &Job{state: model.ImportJob{
+		ID:          id,
+		Status:      model.ImportPending,
+		Format:      format,
+		DryRun:      dryRun,
+		OnDuplicate: onDuplicate,
+		CreatedAt:   time.Now(),
+		ReportURL:   "/users/import/jobs/" + id + "/report",
+	}}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for key, old := range j.jobs {
+		if finished := old.Snapshot().FinishedAt; finished != nil && time.Since(*finished) > j.retention {
+			delete(j.jobs, key)
+		}
+	}
+	j.jobs[id] = job
+	return job
+}
+
+func (j *Jobs) Get(id string) (*Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return nil, errors.NewNotFoundError(id, "no import job with that id")
+	}
+	return job, nil
+}
+
+// Snapshot returns a copy of the job's progress.
+func (job *Job) Snapshot() model.ImportJob {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.state
+}
+
+func (job *Job) Start() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.state.Status = model.ImportRunning
+}
+
+// Finish marks the job completed, or failed when err stopped it early.
+func (job *Job) Finish(err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	now := time.Now()
+	job.state.FinishedAt = &now
+	job.state.Status = model.ImportCompleted
+	if err != nil {
+		job.state.Status = model.ImportFailed
+		job.state.Error = err.Error()
+	}
+}
+
+func (job *Job) Created() { job.count(&job.state.Created) }
+func (job *Job) Updated() { job.count(&job.state.Updated) }
+func (job *Job) Skipped() { job.count(&job.state.Skipped) }
+
+func (job *Job) count(n *int) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	*n++
+	job.state.Processed++
+}
+
+// Fail records why a row was rejected, one report line per field violation.
+func (job *Job) Fail(line int, email string, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.state.Failed++
+	job.state.Processed++
+	job.errors = append(job.errors, rowErrors(line, email, err)...)
+}
+
+// Malformed records a row that could not be parsed.
+func (job *Job) Malformed(line int, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.state.Failed++
+	job.state.Processed++
+	job.errors = append(job.errors, model.ImportRowError{Row: line, Code: "invalid_row", Message: err.Error()})
+}
+
+// WriteReport writes the error report as CSV.
+func (job *Job) WriteReport(w io.Writer) error {
+	job.mu.Lock()
+	rows := append([]model.ImportRowError(nil), job.errors...)
+	job.mu.Unlock()
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"row", "email", "field", "code", "message"})
+	for _, e := range rows {
+		cw.Write([]string{strconv.Itoa(e.Row), e.Email, e.Field, e.Code, e.Message})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func rowErrors(line int, email string, err error) []model.ImportRowError {
+	var (
+		validation *errors.ValidationError
+		duplicate  *errors.DuplicateError
+		notFound   *errors.NotFoundError
+	)
+	base := model.ImportRowError{Row: line, Email: email}
+	switch {
+	case stderrors.As(err, &validation):
+		rows := make([]model.ImportRowError, 0, len(validation.Violations))
+		for _, v := range validation.Violations {
+			e := base
+			e.Field, e.Code, e.Message = v.Field, v.Code, v.Message
+			rows = append(rows, e)
+		}
+		return rows
+	case stderrors.As(err, &duplicate):
+		base.Field, base.Code, base.Message = fmtResource(duplicate.Resource), "duplicate", duplicate.Error()
+	case stderrors.As(err, &notFound):
+		base.Code, base.Message = "not_found", notFound.Error()
+	default:
+		slog.Error("import row failed", "error", err, "row", line)
+		base.Code, base.Message = "internal_error", "the row could not be stored"
+	}
+	return []model.ImportRowError{base}
+}
+
+func fmtResource(resource interface{}) string {
+	if s, ok := resource.(string); ok {
+		return s
+	}
+	return ""
+}
\ No newline at end of file
@@ -0,0 +1,178 @@
+// Package imports reads user rows from uploaded files and tracks import jobs.
+package imports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+	"user-management/internal/model"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Row is one record of the file. Err is set when the record could not be
+// parsed; the reader moves on to the next one. Patch holds only the fields
+// the record has, for updating an existing user without blanking the rest.
+type Row struct {
+	Line  int
+	User  model.CreateUserRequest
+	Patch model.UserPatch
+	Err   error
+}
+
+// Reader yields rows until it returns io.EOF. Any other error means the file
+// cannot be read any further.
+type Reader interface {
+	Next() (Row, error)
+}
+
+// fields are the user attributes a column can map to.
+var fields = []string{"username", "email", "password", "name"}
+
+// CSVReader reads a CSV file whose first line names the columns.
+type CSVReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// NewCSVReader reads the header line and matches columns to fields by name,
+// ignoring case. mapping renames file columns first, e.g. {"Work Email":
+// "email"}. Columns that match no field are ignored; an email column is
+// required.
+func NewCSVReader(r io.Reader, mapping map[string]string) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read csv header %w", err)
+	}
+	renamed := make(map[string]string, len(mapping))
+	for from, to := range mapping {
+		renamed[strings.ToLower(strings.TrimSpace(from))] = strings.ToLower(strings.TrimSpace(to))
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if to, ok := renamed[name]; ok {
+			name = to
+		}
+		for _, f := range fields {
+			if name == f {
+				columns[f] = i
+			}
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("csv header has no email column")
+	}
+	return &CSVReader{r: cr, columns: columns}, nil
+}
+
+func (c *CSVReader) Next() (Row, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return Row{}, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if stderrors.As(err, &parseErr) {
+			return Row{Line: parseErr.Line, Err: err}, nil
+		}
+		return Row{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+	value := func(field string) string {
+		i, ok := c.columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+	present := func(field string) *string {
+		if _, ok := c.columns[field]; !ok {
+			return nil
+		}
+		v := value(field)
+		return &v
+	}
+	return Row{Line: line, User: model.CreateUserRequest{
+		Username: value("username"),
+		Email:    value("email"),
+		Password: value("password"),
+		Name:     value("name"),
+	}, Patch: model.UserPatch{
+		Username: present("username"),
+		Email:    present("email"),
+		Name:     present("name"),
+	}}, nil
+}
+
+// NDJSONReader reads one JSON user object per line. Blank lines are skipped.
+type NDJSONReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &NDJSONReader{s: s}
+}
+
+func (n *NDJSONReader) Next() (Row, error) {
+	for n.s.Scan() {
+		n.line++
+		text := strings.TrimSpace(n.s.Text())
+		if text == "" {
+			continue
+		}
+		row := Row{Line: n.line}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row.User); err != nil {
+			row.Err = err
+		} else {
+			// the same object again, this time noting which keys it has
+			json.Unmarshal([]byte(text), &row.Patch)
+		}
+		return row, nil
+	}
+	if err := n.s.Err(); err != nil {
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
+
+// ParseColumnMapping reads a mapping such as "Work Email=email,Login=username".
+func ParseColumnMapping(header string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		from, to, ok := strings.Cut(pair, "=")
+		to = strings.ToLower(strings.TrimSpace(to))
+		if !ok || strings.TrimSpace(from) == "" || !isField(to) {
+			return nil, fmt.Errorf("invalid column mapping %q, use <column>=<field> with field one of %s", pair, strings.Join(fields, ", "))
+		}
+		mapping[strings.TrimSpace(from)] = to
+	}
+	return mapping, nil
+}
+
+func isField(name string) bool {
+	for _, f := range fields {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -2,31 +2,98 @@ package middleware
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"strings"
 	"user-management/internal/auth"
 	"user-management/internal/config"
+	"user-management/internal/model"
+	"user-management/internal/problem"
 )
 
-func JWTMiddleware(next http.Handler)http.Handler{
+type contextKey string
+
+const claimsKey contextKey="claims"
+
+// SessionValidator rejects tokens that are well formed but no longer valid,
+// for example because the password changed after the token was issued.
+type SessionValidator interface{
+	CheckSession(claims *model.AccessClaims) error
+}
+
+type Authenticator struct{
+	cfg *config.Config
+	sessions SessionValidator
+}
+
+func NewAuthenticator(cfg *config.Config,sessions SessionValidator) *Authenticator{
+	return &Authenticator{cfg: cfg,sessions: sessions}
+}
+
+// JWTMiddleware only admits full access tokens.
+func (a *Authenticator) JWTMiddleware(next http.Handler)http.Handler{
+	return a.jwtMiddleware(next,false)
+}
+
+// PasswordChangeJWTMiddleware also admits tokens issued for an expired
+// password. Use it only on routes that let the user change their password.
+func (a *Authenticator) PasswordChangeJWTMiddleware(next http.Handler)http.Handler{
+	return a.jwtMiddleware(next,true)
+}
+
+// OptionalJWTMiddleware checks a token like JWTMiddleware when one is sent
+// and lets requests without an Authorization header through anonymously.
+// Handlers behind it must check ClaimsFromContext themselves.
+func (a *Authenticator) OptionalJWTMiddleware(next http.Handler)http.Handler{
+	authenticated:=a.jwtMiddleware(next,false)
+	return http.HandlerFunc(func(w http.ResponseWriter,r *http.Request){
+		if r.Header.Get("Authorization")==""{
+			next.ServeHTTP(w,r)
+			return
+		}
+		authenticated.ServeHTTP(w,r)
+	})
+}
+
+func (a *Authenticator) jwtMiddleware(next http.Handler,allowPasswordChange bool)http.Handler{
 	return http.HandlerFunc(func(w http.ResponseWriter,r *http.Request){
 		authHeader:=r.Header.Get("Authorization")
 		if authHeader==""{
-			http.Error(w,`{"error":"Authorization header required"}`,http.StatusUnauthorized)
+			problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"authorization header required")
 			return
 		}
 		if !strings.HasPrefix(authHeader,"Bearer "){
-			http.Error(w,`{"error":"Invalid auth format. Use: Bearer <token>}`,http.StatusUnauthorized)
+			problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"invalid auth format, use: Bearer <token>")
 			return
 		}
 
 		tokenString:=strings.TrimPrefix(authHeader,"Bearer ")
-		claims,err:=auth.ValidateToken(tokenString,config.LoadConfig())
+		claims,err:=auth.ValidateToken(tokenString,a.cfg)
 		if err!=nil{
-			http.Error(w,`{"error":"Invalid or expired token}`,http.StatusUnauthorized)
+			problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"invalid or expired token")
+			return
+		}
+		if err:=a.sessions.CheckSession(claims);err!=nil{
+			slog.Warn("rejected revoked session","error",err,"subject",claims.Subject)
+			problem.Write(w,r,http.StatusUnauthorized,problem.CodeUnauthorized,"invalid or expired token")
 			return
 		}
-		ctx:=context.WithValue(r.Context(),"claims",claims)
-		next.ServeHTTP(w,r.WithContext(ctx))
+		if claims.Scope==model.ScopePasswordChange && !allowPasswordChange{
+			problem.Write(w,r,http.StatusForbidden,problem.CodePasswordExpired,"password expired, change your password to continue")
+			return
+		}
+		next.ServeHTTP(w,r.WithContext(WithClaims(r.Context(),claims)))
 	})
+}
+
+// WithClaims stores the claims of an authenticated caller. Transports other
+// than HTTP use it so handlers find the claims in the same place.
+func WithClaims(ctx context.Context,claims *model.AccessClaims)context.Context{
+	return context.WithValue(ctx,claimsKey,claims)
+}
+
+// ClaimsFromContext returns the claims of the token that authenticated the request.
+func ClaimsFromContext(ctx context.Context)(*model.AccessClaims,bool){
+	claims,ok:=ctx.Value(claimsKey).(*model.AccessClaims)
+	return claims,ok
 }
\ No newline at end of file
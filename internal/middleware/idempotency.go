@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+	"user-management/internal/repository"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response served from the stored result.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// bodies are buffered to fingerprint them before the handler runs
+	maxIdempotentBody = 10 << 20
+)
+
+// Idempotency makes POST, PATCH and DELETE requests that carry an
+// Idempotency-Key safe to retry. The first request runs and its response is
+// stored for the TTL; retries with the same key and body get that response
+// again. Reusing a key with a different request is rejected with 422, and a
+// retry that arrives while the first request is still running gets 409.
+//
+// Keys are scoped to the token subject, so install it after the JWT
+// middleware on authenticated routes. Unauthenticated requests share one
+// anonymous scope: two clients that pick the same key collide, and the second
+// gets 422 or, for an identical request, the first one's response. Clients
+// are expected to use random keys there.
+//
+// Responses are stored as they are, so do not install it on routes whose
+// response carries a token or other secret.
+type Idempotency struct {
+	repo repository.IdempotencyRepo
+	ttl  time.Duration
+}
+
+func NewIdempotency(repo repository.IdempotencyRepo, ttl time.Duration) *Idempotency {
+	return &Idempotency{repo: repo, ttl: ttl}
+}
+
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !idempotentMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidIdempotencyKey, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "unable to read request body")
+			return
+		}
+		if len(body) > maxIdempotentBody {
+			problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, "request body too large to use with Idempotency-Key")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rec := model.IdempotencyRecord{
+			Scope:       idempotencyScope(r.Context()),
+			Key:         key,
+			Fingerprint: fingerprint(r, body),
+			ExpiresAt:   time.Now().Add(i.ttl),
+		}
+		existing, claimed, err := i.repo.ClaimIdempotencyKey(rec)
+		if err != nil {
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "an unexpected error occurred")
+			return
+		}
+		if !claimed {
+			replay(w, r, rec, existing)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// a panic or server error leaves nothing worth replaying
+			if !completed {
+				i.repo.ReleaseIdempotencyKey(rec.Scope, rec.Key)
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+		if recorder.status >= 500 {
+			return
+		}
+		rec.StatusCode = recorder.status
+		rec.ContentType = recorder.Header().Get("Content-Type")
+		rec.Location = recorder.Header().Get("Location")
+		rec.Body = recorder.body.Bytes()
+		if err := i.repo.CompleteIdempotencyKey(rec); err != nil {
+			return
+		}
+		completed = true
+	})
+}
+
+// RunCleanup deletes expired keys every interval until ctx is cancelled.
+func (i *Idempotency) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := i.repo.PurgeExpiredIdempotencyKeys(); err == nil && n > 0 {
+				slog.Info("purged expired idempotency keys", "count", n)
+			}
+		}
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, rec model.IdempotencyRecord, existing *model.IdempotencyRecord) {
+	switch {
+	case existing.Fingerprint != rec.Fingerprint:
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+	case !existing.Completed:
+		w.Header().Set("Retry-After", "1")
+		problem.Write(w, r, http.StatusConflict, problem.CodeIdempotencyInProgress, "a request with this Idempotency-Key is still being processed")
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		if existing.Location != "" {
+			w.Header().Set("Location", existing.Location)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.Body)
+	}
+}
+
+func idempotentMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPatch || method == http.MethodDelete
+}
+
+func idempotencyScope(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return "user:" + claims.Subject
+	}
+	return "anonymous"
+}
+
+// fingerprint identifies the request a key was first used with.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
\ No newline at end of file
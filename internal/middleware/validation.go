@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"user-management/internal/errors"
+	"user-management/internal/openapi"
+	"user-management/internal/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// maxValidatedBody bounds the bodies buffered for validation. Larger
+// uploads only go to the import endpoint, whose body is not JSON.
+const maxValidatedBody = 10 << 20
+
+// RequestValidator checks requests against the OpenAPI document before the
+// handler runs and answers 400 with every violation found. Routes missing
+// from the document pass through; openapi.Verify keeps that from happening.
+//
+// With response validation on, JSON responses are checked as well and
+// differences are logged. The response is sent unchanged, so it is meant
+// for development, to catch drift between the handlers and the spec.
+type RequestValidator struct {
+	doc               *openapi.Document
+	validateResponses bool
+}
+
+func NewRequestValidator(doc *openapi.Document, validateResponses bool) *RequestValidator {
+	return &RequestValidator{doc: doc, validateResponses: validateResponses}
+}
+
+// Middleware must run after the router has matched the route, so install
+// it with Use on a router or subrouter.
+func (v *RequestValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := v.lookup(r)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		violations := v.doc.ValidateParams(op, r.URL.Query(), mux.Vars(r))
+		if op.RequestBody != nil && r.Body != nil && isJSON(r.Header.Get("Content-Type")) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "unable to read request body")
+				return
+			}
+			if len(body) > maxValidatedBody {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, "request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			bodyViolations, err := v.doc.ValidateBody(op, r.Header.Get("Content-Type"), body)
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, err.Error())
+				return
+			}
+			violations = append(violations, bodyViolations...)
+		}
+		if len(violations) > 0 {
+			problem.WriteError(w, r, errors.NewFieldValidationError(violations...))
+			return
+		}
+		if !v.validateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+		recorder := &validatingRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.skip {
+			return
+		}
+		contentType := recorder.Header().Get("Content-Type")
+		if drift := v.doc.ValidateResponse(op, recorder.status, contentType, recorder.body.Bytes()); len(drift) > 0 {
+			slog.Error("response does not match the openapi spec",
+				"operation", op.OperationID,
+				"status", recorder.status,
+				"violations", drift,
+			)
+		}
+	})
+}
+
+func (v *RequestValidator) lookup(r *http.Request) *openapi.Operation {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	return v.doc.Find(r.Method, template)
+}
+
+// isJSON reports whether a request body should be validated as JSON; a
+// missing content type counts, since handlers decode those bodies as JSON.
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	return mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// validatingRecorder keeps a copy of JSON responses. Other responses, like
+// exports and event streams, may be endless and are not buffered.
+type validatingRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	skip        bool
+	body        bytes.Buffer
+}
+
+func (vr *validatingRecorder) WriteHeader(status int) {
+	if !vr.wroteHeader {
+		vr.status = status
+		vr.wroteHeader = true
+		vr.skip = !isJSON(vr.Header().Get("Content-Type"))
+	}
+	vr.ResponseWriter.WriteHeader(status)
+}
+
+func (vr *validatingRecorder) Write(b []byte) (int, error) {
+	if !vr.wroteHeader {
+		vr.WriteHeader(http.StatusOK)
+	}
+	if !vr.skip {
+		vr.body.Write(b)
+	}
+	return vr.ResponseWriter.Write(b)
+}
+
+func (vr *validatingRecorder) Unwrap() http.ResponseWriter {
+	return vr.ResponseWriter
+}
\ No newline at end of file
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"user-management/internal/config"
+)
+
+// MediaTypeV2 in Accept selects the /v2 routes for an unversioned path.
+const MediaTypeV2 = "application/vnd.users.v2+json"
+
+// versionedPaths are the unversioned paths that NegotiateVersion routes.
+var versionedPaths = []string{"/users", "/auth", "/webhooks"}
+
+// NegotiateVersion sends requests for unversioned user, auth and webhook
+// paths to /v2 when Accept asks for MediaTypeV2 and to /v1 otherwise, so
+// clients written before versioning keep working. Routes are matched on the
+// rewritten path, so it wraps the router rather than being installed on it.
+func NegotiateVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !unversioned(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		prefix := "/v1"
+		if acceptsV2(r.Header.Values("Accept")) {
+			prefix = "/v2"
+		}
+		w.Header().Add("Vary", "Accept")
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = prefix + r.URL.Path
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = prefix + r.URL.RawPath
+		}
+		next.ServeHTTP(w, r2)
+	})
+}
+
+func unversioned(path string) bool {
+	for _, p := range versionedPaths {
+		if path == p || strings.HasPrefix(path, p+"/") || strings.HasPrefix(path, p+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsV2 reports whether MediaTypeV2 is among the accepted media types
+// with a non-zero quality.
+func acceptsV2(accept []string) bool {
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || mediaType != MediaTypeV2 {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Deprecation marks every response of a deprecated API version with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers and links the same
+// path in the successor version.
+type Deprecation struct {
+	cfg       config.DeprecationConfig
+	prefix    string
+	successor string
+}
+
+// NewDeprecation deprecates the routes under prefix in favor of the same
+// routes under successor, e.g. /v1 and /v2.
+func NewDeprecation(cfg config.DeprecationConfig, prefix, successor string) *Deprecation {
+	return &Deprecation{cfg: cfg, prefix: prefix, successor: successor}
+}
+
+func (d *Deprecation) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.cfg.DeprecatedAt.Unix()))
+		if !d.cfg.Sunset.IsZero() {
+			h.Set("Sunset", d.cfg.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if path, ok := strings.CutPrefix(r.URL.Path, d.prefix); ok {
+			h.Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", d.successor, path))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
\ No newline at end of file
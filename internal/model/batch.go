@@ -0,0 +1,51 @@
+package model
+
+import "user-management/internal/errors"
+
+const (
+	// BatchAllOrNothing applies every operation or none of them.
+	BatchAllOrNothing = "all_or_nothing"
+	// BatchBestEffort applies each valid operation on its own.
+	BatchBestEffort = "best_effort"
+
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is one create, update or delete. ID is required for update
+// and delete; User is required for create and update and must not carry a
+// password on update.
+type BatchOperation struct {
+	Op   string             `json:"op"`
+	ID   int                `json:"id,omitempty"`
+	User *CreateUserRequest `json:"user,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string              `json:"mode"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Results   []BatchItemResponse `json:"results"`
+}
+
+// BatchItemResponse reports one operation, in request order. Status is the
+// HTTP status the equivalent single request would have returned.
+type BatchItemResponse struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	Status int           `json:"status"`
+	User   *UserResponse `json:"user,omitempty"`
+	Error  *BatchError   `json:"error,omitempty"`
+}
+
+type BatchError struct {
+	Code   string                  `json:"code"`
+	Detail string                  `json:"detail,omitempty"`
+	Errors []errors.FieldViolation `json:"errors,omitempty"`
+}
\ No newline at end of file
@@ -0,0 +1,142 @@
+package model
+
+// Request and response shapes for the HTTP API. User itself is the domain
+// type and is never encoded directly, so secret fields cannot leak.
+
+type CreateUserRequest struct{
+	Username string `json:"username"`
+	Email string `json:"email"`
+	Password string `json:"password"`
+	Name string `json:"name"`
+}
+
+func (r CreateUserRequest) ToUser() User{
+	return User{
+		Username: r.Username,
+		Email: r.Email,
+		Password: r.Password,
+		Name: r.Name,
+	}
+}
+
+type UpdateUserRequest struct{
+	Username string `json:"username"`
+	Email string `json:"email"`
+	Name string `json:"name"`
+}
+
+func (r UpdateUserRequest) ToUser() User{
+	return User{
+		Username: r.Username,
+		Email: r.Email,
+		Name: r.Name,
+	}
+}
+
+type UserResponse struct{
+	ID int `json:"id"`
+	Username string `json:"username"`
+	Email string `json:"email"`
+	Name string `json:"name,omitempty"`
+	IsActive bool `json:"isactive"`
+}
+
+func NewUserResponse(u *User) UserResponse{
+	return UserResponse{
+		ID: u.ID,
+		Username: u.Username,
+		Email: u.Email,
+		Name: u.Name,
+		IsActive: u.IsActive,
+	}
+}
+
+func NewUserResponses(users []User) []UserResponse{
+	responses:=make([]UserResponse,0,len(users))
+	for i:=range users{
+		responses=append(responses,NewUserResponse(&users[i]))
+	}
+	return responses
+}
+
+// The /v2 shapes replace name with given_name and family_name. Password is
+// accepted on create only and, as in v1, never returned.
+
+type CreateUserRequestV2 struct{
+	Username string `json:"username"`
+	Email string `json:"email"`
+	Password string `json:"password"`
+	GivenName string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+}
+
+func (r CreateUserRequestV2) ToUser() User{
+	return User{
+		Username: r.Username,
+		Email: r.Email,
+		Password: r.Password,
+		GivenName: r.GivenName,
+		FamilyName: r.FamilyName,
+	}
+}
+
+type UpdateUserRequestV2 struct{
+	Username string `json:"username"`
+	Email string `json:"email"`
+	GivenName string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+}
+
+func (r UpdateUserRequestV2) ToUser() User{
+	return User{
+		Username: r.Username,
+		Email: r.Email,
+		GivenName: r.GivenName,
+		FamilyName: r.FamilyName,
+	}
+}
+
+// UserPatchV2 is the partial update of /v2. Nil fields are left unchanged.
+type UserPatchV2 struct{
+	Username *string `json:"username"`
+	Email *string `json:"email"`
+	GivenName *string `json:"given_name"`
+	FamilyName *string `json:"family_name"`
+}
+
+func (p UserPatchV2) ToPatch() UserPatch{
+	return UserPatch{
+		Username: p.Username,
+		Email: p.Email,
+		GivenName: p.GivenName,
+		FamilyName: p.FamilyName,
+	}
+}
+
+type UserResponseV2 struct{
+	ID int `json:"id"`
+	Username string `json:"username"`
+	Email string `json:"email"`
+	GivenName string `json:"given_name,omitempty"`
+	FamilyName string `json:"family_name,omitempty"`
+	IsActive bool `json:"isactive"`
+}
+
+func NewUserResponseV2(u *User) UserResponseV2{
+	return UserResponseV2{
+		ID: u.ID,
+		Username: u.Username,
+		Email: u.Email,
+		GivenName: u.GivenName,
+		FamilyName: u.FamilyName,
+		IsActive: u.IsActive,
+	}
+}
+
+func NewUserResponsesV2(users []User) []UserResponseV2{
+	responses:=make([]UserResponseV2,0,len(users))
+	for i:=range users{
+		responses=append(responses,NewUserResponseV2(&users[i]))
+	}
+	return responses
+}
\ No newline at end of file
@@ -0,0 +1,31 @@
+package model
+
+import "strings"
+
+// UserFilter narrows user listings. The zero value matches every user.
+type UserFilter struct {
+	// Active, when set, keeps only active or only inactive users.
+	Active *bool
+	// Search matches a substring of the username, email or name, ignoring case.
+	Search string
+	// AfterID keeps users with a greater id, for keyset pagination.
+	AfterID int
+}
+
+// Matches reports whether u passes the filter, for stores that cannot
+// filter in a query.
+func (f UserFilter) Matches(u User) bool {
+	if f.Active != nil && u.IsActive != *f.Active {
+		return false
+	}
+	if u.ID <= f.AfterID {
+		return false
+	}
+	if f.Search == "" {
+		return true
+	}
+	search := strings.ToLower(f.Search)
+	return strings.Contains(strings.ToLower(u.Username), search) ||
+		strings.Contains(strings.ToLower(u.Email), search) ||
+		strings.Contains(strings.ToLower(u.Name), search)
+}
\ No newline at end of file
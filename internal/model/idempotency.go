@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request made with an
+// Idempotency-Key. Completed is false while the first request is running.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Location    string
+	Body        []byte
+	ExpiresAt   time.Time
+}
\ No newline at end of file
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+
+	// OnDuplicateSkip leaves users whose email already exists untouched.
+	OnDuplicateSkip = "skip"
+	// OnDuplicateUpsert updates their username and name from the row.
+	OnDuplicateUpsert = "upsert"
+)
+
+// ImportJob tracks one POST /users/import. In a dry run the counts say what
+// would have happened and nothing is written.
+type ImportJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	DryRun      bool       `json:"dry_run"`
+	OnDuplicate string     `json:"on_duplicate"`
+	Processed   int        `json:"processed"`
+	Created     int        `json:"created"`
+	Updated     int        `json:"updated"`
+	Skipped     int        `json:"skipped"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ReportURL   string     `json:"report_url"`
+}
+
+// ImportRowError is one line of the error report. Row is the line number in
+// the uploaded file, counting the CSV header.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
\ No newline at end of file
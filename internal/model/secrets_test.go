@@ -0,0 +1,74 @@
+package model_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"user-management/internal/events"
+	"user-management/internal/model"
+	"user-management/internal/scim"
+)
+
+// The stored password is the hash; the plaintext is what a client sent.
+const (
+	plaintext = "Correct-Horse-9"
+	hash      = "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0$aGFzaGhhc2hoYXNoaGFzaA"
+)
+
+func secretUser() *model.User {
+	return &model.User{
+		ID:                7,
+		Username:          "ada",
+		Email:             "ada@example.com",
+		Password:          hash,
+		Name:              "Ada Lovelace",
+		GivenName:         "Ada",
+		FamilyName:        "Lovelace",
+		IsActive:          true,
+		PasswordChangedAt: time.Now(),
+	}
+}
+
+// TestSecretsNeverSerialized fails when a type the API or an event sink
+// encodes starts carrying the password hash.
+func TestSecretsNeverSerialized(t *testing.T) {
+	u := secretUser()
+	plain := *u
+	plain.Password = plaintext
+
+	event, err := events.New(u.ID, events.UserCreated{User: model.NewUserResponse(u)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]any{
+		"User":                u,
+		"User with plaintext": &plain,
+		"UserResponse":        model.NewUserResponse(u),
+		"UserResponses":       model.NewUserResponses([]model.User{*u}),
+		"UserResponseV2":      model.NewUserResponseV2(u),
+		"UserResponsesV2":     model.NewUserResponsesV2([]model.User{*u}),
+		"LoginResponse":       model.LoginResponse{User: &model.UserResponse{ID: u.ID}, Token: "t"},
+		"UserCreated":         events.UserCreated{User: model.NewUserResponse(u)},
+		"UserUpdated":         events.UserUpdated{User: model.NewUserResponse(u)},
+		"UserPasswordChanged": events.UserPasswordChanged{},
+		"Event":               event,
+		"SCIM User":           scim.FromUser(u),
+		"SCIM User plaintext": scim.FromUser(&plain),
+		"SCIM ListResponse":   scim.NewListResponse([]scim.User{scim.FromUser(u)}, 1, 1),
+	}
+	for name, v := range cases {
+		t.Run(name, func(t *testing.T) {
+			out, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := string(out)
+			for _, secret := range []string{hash, plaintext, "$argon2id$", `"password"`} {
+				if strings.Contains(s, secret) {
+					t.Errorf("output contains %q: %s", secret, s)
+				}
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -1,29 +1,84 @@
 package model
 
 import (
-		"github.com/golang-jwt/jwt/v5"
+	"strings"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 )
+// User is the domain type. Handlers respond with UserResponse; the secret
+// fields are still tagged json:"-" in case a User is ever encoded by mistake.
 type User struct{
 	ID int 	`json:"id"`
-	Username string `json:"username"`
-	Email string `json:"email"`
-	Password string `json:"password"`
-	Name string `json:"name,omitempty"`
+	Username string `json:"username" validate:"required,min=3,max=32,username"`
+	Email string `json:"email" validate:"required,max=254,email"`
+	Password string `json:"-" validate:"required"`
+	Name string `json:"name,omitempty" validate:"max=100"`
+	// GivenName and FamilyName are what v2 exposes instead of Name. The
+	// service keeps the three in step, see SplitName and JoinName.
+	GivenName string `json:"given_name,omitempty" validate:"max=100"`
+	FamilyName string `json:"family_name,omitempty" validate:"max=100"`
 	IsActive bool `json:"isactive,omitempty"`
+	PasswordChangedAt time.Time `json:"-"`
+}
+
+// SplitName splits a formatted name at its first space, which is how names
+// written without given and family names are stored.
+func SplitName(name string) (given, family string){
+	given,family,_=strings.Cut(strings.TrimSpace(name)," ")
+	return given,strings.TrimSpace(family)
+}
+
+// JoinName formats given and family names as one name.
+func JoinName(given, family string) string{
+	return strings.TrimSpace(given+" "+family)
 }
 
 type AccessClaims struct{
 	Email string `json:"email"`
+	// Scope is empty for full access tokens. Tokens issued for an expired
+	// password carry ScopePasswordChange and can only change the password.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+const ScopePasswordChange = "password_change"
+
 type LoginRequest struct{
 	Email string `json:"email"`
 	Password string `json:"password"`
 }
 type LoginResponse struct{
-	User User  `json:"user"`
+	User *UserResponse `json:"user,omitempty"`
 	Token string `json:"token"`
 	Message string `json:"message"`
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+}
+
+type ChangePasswordRequest struct{
+	CurrentPassword string `json:"current_password"`
+	NewPassword string `json:"new_password"`
+}
+type ChangePasswordResponse struct{
+	Token string `json:"token"`
+	Message string `json:"message"`
+}
+
+// UserPatch holds the fields of a partial update. Nil fields are left unchanged.
+type UserPatch struct{
+	Username *string `json:"username"`
+	Email *string `json:"email"`
+	Name *string `json:"name"`
+	// GivenName and FamilyName are only set through UserPatchV2.
+	GivenName *string `json:"-"`
+	FamilyName *string `json:"-"`
+}
+
+type SessionResponse struct{
+	Subject string `json:"sub"`
+	Email string `json:"email"`
+	Scope string `json:"scope,omitempty"`
+	IssuedAt time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+	ExpiresIn int64 `json:"expires_in"`
 }
\ No newline at end of file
@@ -0,0 +1,90 @@
+package model
+
+import "time"
+
+// Webhook is a partner subscription to domain events. An empty EventTypes
+// list subscribes to every event.
+type Webhook struct{
+	ID int
+	URL string
+	EventTypes []string
+	Secret string
+	Active bool
+	ConsecutiveFailures int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const (
+	DeliveryPending = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed = "failed"
+)
+
+// WebhookDelivery tracks one event sent to one webhook across all its attempts.
+type WebhookDelivery struct{
+	ID int64 `json:"id"`
+	WebhookID int `json:"webhook_id"`
+	EventID int64 `json:"event_id"`
+	EventType string `json:"event_type"`
+	Payload []byte `json:"-"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastStatusCode int `json:"last_status_code,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookAttempt struct{
+	DeliveryID int64 `json:"delivery_id"`
+	Attempt int `json:"attempt"`
+	StatusCode int `json:"status_code,omitempty"`
+	Error string `json:"error,omitempty"`
+	DurationMs int64 `json:"duration_ms"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct{
+	URL string `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is generated when empty.
+	Secret string `json:"secret"`
+}
+
+type UpdateWebhookRequest struct{
+	URL string `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active bool `json:"active"`
+}
+
+// WebhookResponse omits the secret, which is only returned once on creation.
+type WebhookResponse struct{
+	ID int `json:"id"`
+	URL string `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active bool `json:"active"`
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	Secret string `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhookResponse(w *Webhook) WebhookResponse{
+	eventTypes:=w.EventTypes
+	if eventTypes==nil{
+		eventTypes=[]string{}
+	}
+	return WebhookResponse{
+		ID: w.ID,
+		URL: w.URL,
+		EventTypes: eventTypes,
+		Active: w.Active,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+type WebhookDeliveryResponse struct{
+	WebhookDelivery
+	History []WebhookAttempt `json:"history,omitempty"`
+}
\ No newline at end of file
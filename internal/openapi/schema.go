@@ -0,0 +1,182 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// packageOptions name and shape the components of every type in a package.
+type packageOptions struct {
+	// prefix is prepended to component names.
+	prefix string
+	// open leaves additionalProperties unset, for packages whose types
+	// are decoded leniently.
+	open bool
+}
+
+// generator turns Go types into schemas. Named struct types become
+// components referenced with $ref; field names come from the json tags
+// and constraints from the validate tags, as internal/validation reads them.
+type generator struct {
+	schemas map[string]*Schema
+	// rules lends the validate tags of one struct to another with fields of
+	// the same Go name, for request types checked as a model.User.
+	rules map[reflect.Type]reflect.Type
+	// required lists the properties a request must send beyond those
+	// tagged required, for types that are checked in code.
+	required map[reflect.Type][]string
+	// optional types have no required properties, for types whose
+	// required fields depend on where they are used.
+	optional map[reflect.Type]bool
+	// fieldTypes describes a struct field, keyed "Type.Field", as another
+	// type.
+	fieldTypes map[string]reflect.Type
+	// names overrides component names, e.g. for instances of generic types.
+	names map[reflect.Type]string
+	// packages is keyed by import path.
+	packages map[string]packageOptions
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas:    map[string]*Schema{},
+		rules:      map[reflect.Type]reflect.Type{},
+		required:   map[reflect.Type][]string{},
+		optional:   map[reflect.Type]bool{},
+		fieldTypes: map[string]reflect.Type{},
+		names:      map[reflect.Type]string{},
+		packages:   map[string]packageOptions{},
+	}
+}
+
+// ref returns the schema of v's type, registering components as needed.
+func (g *generator) ref(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == rawType:
+		// any JSON value
+		return &Schema{}
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object"}
+	case t.Kind() == reflect.Slice:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		name := g.name(t)
+		if _, ok := g.schemas[name]; !ok {
+			// registered first so recursive types terminate
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (g *generator) name(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	return g.packages[t.PkgPath()].prefix + t.Name()
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if !g.packages[t.PkgPath()].open {
+		closed := false
+		s.AdditionalProperties = &closed
+	}
+	g.fields(t, s)
+	s.Required = append(s.Required, g.required[t]...)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			// embedded fields are flattened, as encoding/json does
+			g.fields(sf.Type, s)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		ft := sf.Type
+		if override, ok := g.fieldTypes[t.Name()+"."+sf.Name]; ok {
+			ft = override
+		}
+		prop := g.schema(ft)
+		// a pointer sent as null is treated as absent
+		if sf.Type.Kind() == reflect.Pointer && !strings.Contains(opts, "omitempty") && prop.Ref == "" {
+			prop.Nullable = true
+		}
+		if g.applyRules(t, sf, prop) && sf.Type.Kind() != reflect.Pointer && !g.optional[t] {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// applyRules adds the validate constraints of the field to prop and reports
+// whether the field is required.
+func (g *generator) applyRules(t reflect.Type, sf reflect.StructField, prop *Schema) bool {
+	tag := sf.Tag.Get("validate")
+	if source, ok := g.rules[t]; ok {
+		if f, ok := source.FieldByName(sf.Name); ok {
+			tag = f.Tag.Get("validate")
+		}
+	}
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		n, _ := strconv.Atoi(arg)
+		switch name {
+		case "required":
+			required = true
+			if !g.optional[t] {
+				one := 1
+				prop.MinLength = &one
+			}
+		case "min":
+			prop.MinLength = &n
+		case "max":
+			prop.MaxLength = &n
+		case "email":
+			prop.Format = "email"
+		case "username":
+			prop.Description = "Letters, digits, '.', '_' and '-' only."
+		}
+	}
+	return required
+}
\ No newline at end of file
@@ -0,0 +1,735 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"user-management/internal/model"
+	"user-management/internal/problem"
+	"user-management/internal/scim"
+	"user-management/pkg/introspect"
+)
+
+const (
+	// Version is the version of the described API.
+	Version = "1.0.0"
+
+	jsonType = "application/json"
+)
+
+// graphqlRequest and graphqlResponse describe the GraphQL transport; the
+// schema itself is published by introspection on /graphql.
+type graphqlRequest struct {
+	Query         string         `json:"query" validate:"required"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   map[string]any   `json:"data,omitempty"`
+	Errors []map[string]any `json:"errors,omitempty"`
+}
+
+// batchUser is the user of a batch operation. Which of its fields are
+// required depends on the op, so the service checks them.
+type batchUser model.CreateUserRequest
+
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type introspectionRequest struct {
+	Token         string `json:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint,omitempty"`
+}
+
+// apiVersion is one tree of REST routes. The trees share their routes and
+// differ in the user shapes; batch, import, export and events use the v1
+// shape in both.
+type apiVersion struct {
+	prefix     string
+	deprecated bool
+	create     any
+	update     any
+	patch      any
+	user       any
+	users      any
+}
+
+var apiVersions = []apiVersion{
+	{
+		prefix:     "/v1",
+		deprecated: true,
+		create:     model.CreateUserRequest{},
+		update:     model.UpdateUserRequest{},
+		patch:      model.UserPatch{},
+		user:       model.UserResponse{},
+		users:      []model.UserResponse{},
+	},
+	{
+		prefix: "/v2",
+		create: model.CreateUserRequestV2{},
+		update: model.UpdateUserRequestV2{},
+		patch:  model.UserPatchV2{},
+		user:   model.UserResponseV2{},
+		users:  []model.UserResponseV2{},
+	},
+}
+
+// Spec builds the document for every route cmd/server can mount.
+func Spec() *Document {
+	b := newBuilder()
+	for _, v := range apiVersions {
+		b.api = v
+		b.users()
+		b.auth()
+		b.webhooks()
+	}
+	b.api = apiVersion{}
+	b.graphql()
+	b.introspection()
+	b.scim()
+	b.docs()
+	return b.doc
+}
+
+type builder struct {
+	doc *Document
+	gen *generator
+	// api is the version being added; add prefixes its routes and
+	// operation ids.
+	api apiVersion
+}
+
+func newBuilder() *builder {
+	gen := newGenerator()
+	user := reflect.TypeOf(model.User{})
+	for _, v := range []any{
+		model.CreateUserRequest{}, model.UpdateUserRequest{}, model.UserPatch{},
+		model.CreateUserRequestV2{}, model.UpdateUserRequestV2{}, model.UserPatchV2{},
+	} {
+		gen.rules[reflect.TypeOf(v)] = user
+	}
+	gen.required[reflect.TypeOf(model.LoginRequest{})] = []string{"email", "password"}
+	gen.required[reflect.TypeOf(model.ChangePasswordRequest{})] = []string{"current_password", "new_password"}
+	gen.required[reflect.TypeOf(model.CreateWebhookRequest{})] = []string{"url"}
+	gen.required[reflect.TypeOf(model.BatchRequest{})] = []string{"operations"}
+	gen.required[reflect.TypeOf(model.BatchOperation{})] = []string{"op"}
+	gen.rules[reflect.TypeOf(batchUser{})] = user
+	gen.optional[reflect.TypeOf(batchUser{})] = true
+	gen.fieldTypes[field(model.BatchOperation{}, "User")] = reflect.TypeOf(batchUser{})
+	gen.names[reflect.TypeOf(batchUser{})] = "BatchUser"
+	gen.names[reflect.TypeOf(scim.ListResponse[scim.User]{})] = "SCIMUserList"
+	gen.names[reflect.TypeOf(scim.ListResponse[scim.ResourceType]{})] = "SCIMResourceTypeList"
+	gen.names[reflect.TypeOf(scim.ListResponse[scim.SchemaDefinition]{})] = "SCIMSchemaList"
+	gen.names[reflect.TypeOf(introspect.Response{})] = "IntrospectionResponse"
+	gen.names[reflect.TypeOf(introspectionRequest{})] = "IntrospectionRequest"
+	gen.names[reflect.TypeOf(oauthError{})] = "OAuthError"
+	gen.names[reflect.TypeOf(graphqlRequest{})] = "GraphQLRequest"
+	gen.names[reflect.TypeOf(graphqlResponse{})] = "GraphQLResponse"
+	gen.packages[reflect.TypeOf(scim.User{}).PkgPath()] = packageOptions{prefix: "SCIM", open: true}
+
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info: Info{
+			Title:   "User Management API",
+			Version: Version,
+			Description: "Routes marked x-enabled-by are only mounted when the named " +
+				"environment variable is set. Errors are RFC 9457 problem documents " +
+				"with a machine-readable code, except on the SCIM and OAuth routes, " +
+				"which use the error formats of their specifications.\n\n" +
+				"The user, auth and webhook routes are served under /v1 and /v2. " +
+				"v2 replaces name with given_name and family_name. Unversioned " +
+				"paths such as /users are served by /v2 when Accept lists " +
+				"application/vnd.users.v2+json and by /v1 otherwise. Responses " +
+				"of /v1 carry Deprecation and Sunset headers and link their " +
+				"/v2 successor.",
+		},
+		Paths: map[string]*PathItem{},
+		Components: Components{
+			Schemas: gen.schemas,
+			Parameters: map[string]*Parameter{
+				"IdempotencyKey": {
+					Name: "Idempotency-Key",
+					In:   "header",
+					Description: "Makes the request safe to retry for the idempotency TTL. " +
+						"Retries with the same key and body get the stored response.",
+					Schema: &Schema{Type: "string", MaxLength: intPtr(255)},
+				},
+			},
+			Responses: map[string]*Response{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Access token from POST /auth/login.",
+				},
+				"scimToken": {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "The static token in SCIM_BEARER_TOKEN.",
+				},
+				"webhookAdminToken": {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "The operator token in WEBHOOKS_ADMIN_TOKEN.",
+				},
+				"introspectionClient": {
+					Type:        "http",
+					Scheme:      "basic",
+					Description: "Client credentials from INTROSPECTION_CLIENTS.",
+				},
+			},
+		},
+	}
+	b := &builder{doc: doc, gen: gen}
+	for _, status := range []int{
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusNotFound, http.StatusConflict, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity,
+		http.StatusInternalServerError,
+	} {
+		doc.Components.Responses[problemName(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]*MediaType{problem.ContentType: {Schema: gen.ref(problem.Problem{})}},
+		}
+	}
+	doc.Components.Responses["SCIMError"] = &Response{
+		Description: "SCIM error",
+		Content:     map[string]*MediaType{scim.ContentType: {Schema: gen.ref(scim.Error{})}},
+	}
+	return b
+}
+
+// add registers op under a mux path template; path variables with a
+// pattern become constrained parameters.
+func (b *builder) add(method, route string, op *Operation) {
+	if b.api.prefix != "" {
+		version := strings.TrimPrefix(b.api.prefix, "/")
+		route = b.api.prefix + route
+		op.OperationID = version + strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+		op.Deprecated = b.api.deprecated
+	}
+	path, params := pathTemplate(route)
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[path] = item
+	}
+	op.Parameters = append(params, op.Parameters...)
+	(*item)[strings.ToLower(method)] = op
+}
+
+// protected marks op as needing a full access token and idempotency keys.
+func (b *builder) protected(method, path string, op *Operation) {
+	b.idempotent(method, op)
+	b.authenticated(method, path, op)
+}
+
+// authenticated is protected without idempotency keys.
+func (b *builder) authenticated(method, path string, op *Operation) {
+	op.Security = []map[string][]string{{"bearerAuth": {}}}
+	b.add(method, path, op)
+	b.problems(op, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)
+}
+
+// admin marks op as needing the webhook operator token. Idempotency keys are
+// not offered, since a new webhook's response carries its secret.
+func (b *builder) admin(method, path string, op *Operation) {
+	op.Security = []map[string][]string{{"webhookAdminToken": {}}}
+	op.EnabledBy = "WEBHOOKS_ADMIN_TOKEN"
+	b.add(method, path, op)
+	b.problems(op, http.StatusUnauthorized, http.StatusInternalServerError)
+}
+
+func (b *builder) idempotent(method string, op *Operation) {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodDelete:
+		op.Parameters = append(op.Parameters, &Parameter{Ref: "#/components/parameters/IdempotencyKey"})
+		b.problems(op, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+}
+
+func (b *builder) problems(op *Operation, statuses ...int) {
+	if op.Responses == nil {
+		op.Responses = map[string]*Response{}
+	}
+	for _, status := range statuses {
+		op.Responses[strconv.Itoa(status)] = &Response{Ref: "#/components/responses/" + problemName(status)}
+	}
+}
+
+func (b *builder) scimErrors(op *Operation, statuses ...int) {
+	for _, status := range statuses {
+		op.Responses[strconv.Itoa(status)] = &Response{Ref: "#/components/responses/SCIMError"}
+	}
+}
+
+func (b *builder) json(v any) *RequestBody {
+	return b.body(jsonType, b.gen.ref(v))
+}
+
+func (b *builder) body(contentType string, schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]*MediaType{contentType: {Schema: schema}}}
+}
+
+func (b *builder) ok(description string, v any) map[string]*Response {
+	return b.respond(http.StatusOK, description, jsonType, b.gen.ref(v))
+}
+
+func (b *builder) respond(status int, description, contentType string, schema *Schema) map[string]*Response {
+	return map[string]*Response{strconv.Itoa(status): {
+		Description: description,
+		Content:     map[string]*MediaType{contentType: {Schema: schema}},
+	}}
+}
+
+// merge adds responses to op, replacing those for the same status.
+func (b *builder) merge(op *Operation, responses map[string]*Response) {
+	for status, response := range responses {
+		op.Responses[status] = response
+	}
+}
+
+func empty(status int, description string) map[string]*Response {
+	return map[string]*Response{strconv.Itoa(status): {Description: description}}
+}
+
+var (
+	activeParam = &Parameter{Name: "active", In: "query", Description: "Only active or only inactive users.", Schema: &Schema{Type: "boolean"}}
+	searchParam = &Parameter{Name: "q", In: "query", Description: "Case-insensitive match on username, email or name.", Schema: &Schema{Type: "string"}}
+)
+
+func (b *builder) users() {
+	create := &Operation{
+		OperationID: "createUser",
+		Summary:     "Register a user",
+		Tags:        []string{"users"},
+		RequestBody: b.json(b.api.create),
+		Responses:   b.ok("The created user", b.api.user),
+	}
+	b.idempotent(http.MethodPost, create)
+	b.add(http.MethodPost, "/users", create)
+	b.problems(create, http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError)
+
+	b.protected(http.MethodGet, "/users", &Operation{
+		OperationID: "listUsers",
+		Summary:     "List users",
+		Tags:        []string{"users"},
+		Parameters:  []*Parameter{activeParam, searchParam},
+		Responses:   b.ok("Matching users", b.api.users),
+	})
+	export := &Operation{
+		OperationID: "exportUsers",
+		Summary:     "Stream users as CSV, NDJSON or Parquet",
+		Tags:        []string{"users"},
+		Parameters: []*Parameter{
+			{Name: "format", In: "query", Required: true, Schema: &Schema{Type: "string", Enum: []string{"csv", "ndjson", "parquet"}}},
+			{Name: "fields", In: "query", Description: "Comma separated columns; all by default.", Schema: &Schema{Type: "string"}},
+			activeParam, searchParam,
+		},
+		Responses: map[string]*Response{"200": {
+			Description: "The export, as an attachment",
+			Content: map[string]*MediaType{
+				"text/csv":                       {Schema: &Schema{Type: "string"}},
+				"application/x-ndjson":           {Schema: &Schema{Type: "string"}},
+				"application/vnd.apache.parquet": {Schema: &Schema{Type: "string", Format: "binary"}},
+			},
+		}},
+	}
+	b.protected(http.MethodGet, "/users/export", export)
+	b.problems(export, http.StatusBadRequest)
+
+	batch := &Operation{
+		OperationID: "batchUsers",
+		Summary:     "Create, update and delete users in one request",
+		Description: "In all_or_nothing mode (the default) any failure rolls back the batch and " +
+			"answers 422; in best_effort mode partial failure answers 207.",
+		Tags:        []string{"users"},
+		RequestBody: b.json(model.BatchRequest{}),
+		Responses:   b.ok("Every operation succeeded", model.BatchResponse{}),
+	}
+	b.protected(http.MethodPost, "/users:batch", batch)
+	b.problems(batch, http.StatusBadRequest)
+	b.merge(batch, b.respond(http.StatusMultiStatus, "Some operations failed", jsonType, b.gen.ref(model.BatchResponse{})))
+	// a reused Idempotency-Key also answers 422, as a problem
+	b.merge(batch, b.respond(http.StatusUnprocessableEntity, "The batch was rolled back", jsonType, b.gen.ref(model.BatchResponse{})))
+
+	imp := &Operation{
+		OperationID: "importUsers",
+		Summary:     "Import users from CSV or NDJSON",
+		Description: "Small uploads are processed inline and answer 200; larger ones start " +
+			"a background job and answer 202 with its Location.",
+		Tags: []string{"users"},
+		Parameters: []*Parameter{
+			{Name: "dry_run", In: "query", Description: "Validate without writing.", Schema: &Schema{Type: "boolean"}},
+			{Name: "on_duplicate", In: "query", Schema: &Schema{Type: "string", Enum: []string{model.OnDuplicateSkip, model.OnDuplicateUpsert}}},
+			{Name: "X-Import-Columns", In: "header", Description: "Renames CSV columns to user fields, e.g. mail=email,login=username.", Schema: &Schema{Type: "string"}},
+		},
+		RequestBody: &RequestBody{Required: true, Content: map[string]*MediaType{
+			"text/csv":             {Schema: &Schema{Type: "string"}},
+			"application/x-ndjson": {Schema: &Schema{Type: "string"}},
+		}},
+		Responses: b.ok("The finished job", model.ImportJob{}),
+	}
+	started := b.respond(http.StatusAccepted, "The job was started", jsonType, b.gen.ref(model.ImportJob{}))
+	started["202"].Headers = map[string]*Header{"Location": {Schema: &Schema{Type: "string"}}}
+	b.merge(imp, started)
+	// uploads are too large to buffer for idempotency keys
+	b.authenticated(http.MethodPost, "/users/import", imp)
+	b.problems(imp, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+
+	job := &Operation{
+		OperationID: "getImportJob",
+		Summary:     "Get the progress of an import job",
+		Tags:        []string{"users"},
+		Responses:   b.ok("The job", model.ImportJob{}),
+	}
+	b.protected(http.MethodGet, "/users/import/jobs/{id}", job)
+	b.problems(job, http.StatusNotFound)
+	report := &Operation{
+		OperationID: "getImportReport",
+		Summary:     "Download the rejected rows of an import job",
+		Tags:        []string{"users"},
+		Responses: b.respond(http.StatusOK, "One row per rejected input row", "text/csv",
+			&Schema{Type: "string", Description: "Columns: row, email, field, code, message."}),
+	}
+	b.protected(http.MethodGet, "/users/import/jobs/{id}/report", report)
+	b.problems(report, http.StatusNotFound)
+
+	b.protected(http.MethodGet, "/users/events", &Operation{
+		OperationID: "streamUserEvents",
+		Summary:     "Stream user events as server-sent events",
+		Description: "Send Last-Event-ID to resume after a disconnect.",
+		Tags:        []string{"users"},
+		Parameters: []*Parameter{
+			{Name: "Last-Event-ID", In: "header", Schema: &Schema{Type: "string"}},
+		},
+		Responses: b.respond(http.StatusOK, "An endless event stream", "text/event-stream", &Schema{Type: "string"}),
+	})
+
+	b.protected(http.MethodGet, "/users/me", &Operation{
+		OperationID: "getMe",
+		Summary:     "Get the authenticated user",
+		Tags:        []string{"me"},
+		Responses:   b.ok("The user", b.api.user),
+	})
+	patchMe := &Operation{
+		OperationID: "patchMe",
+		Summary:     "Change some fields of the authenticated user",
+		Description: "Absent and null fields are left unchanged.",
+		Tags:        []string{"me"},
+		RequestBody: b.json(b.api.patch),
+		Responses:   b.ok("The updated user", b.api.user),
+	}
+	b.protected(http.MethodPatch, "/users/me", patchMe)
+	b.problems(patchMe, http.StatusBadRequest)
+	b.protected(http.MethodDelete, "/users/me", &Operation{
+		OperationID: "deleteMe",
+		Summary:     "Delete the authenticated user",
+		Tags:        []string{"me"},
+		Responses:   empty(http.StatusOK, "The user was deleted"),
+	})
+
+	get := &Operation{
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Tags:        []string{"users"},
+		Responses:   b.ok("The user", b.api.user),
+	}
+	b.protected(http.MethodGet, "/users/{id:[0-9]+}", get)
+	b.problems(get, http.StatusBadRequest, http.StatusNotFound)
+	update := &Operation{
+		OperationID: "updateUser",
+		Summary:     "Replace the profile of a user",
+		Tags:        []string{"users"},
+		RequestBody: b.json(b.api.update),
+		Responses:   b.ok("The updated user", b.api.user),
+	}
+	b.protected(http.MethodPut, "/users/{id:[0-9]+}", update)
+	b.problems(update, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)
+	del := &Operation{
+		OperationID: "deleteUser",
+		Summary:     "Delete a user",
+		Tags:        []string{"users"},
+		Responses:   empty(http.StatusOK, "The user was deleted"),
+	}
+	b.protected(http.MethodDelete, "/users/{id:[0-9]+}", del)
+	b.problems(del, http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (b *builder) auth() {
+	login := &Operation{
+		OperationID: "login",
+		Summary:     "Exchange credentials for an access token",
+		Description: "When the password has expired the token only allows " +
+			"POST /users/me/password and password_change_required is set.",
+		Tags:        []string{"auth"},
+		RequestBody: b.json(model.LoginRequest{}),
+		Responses:   b.ok("A token", model.LoginResponse{}),
+	}
+	b.add(http.MethodPost, "/auth/login", login)
+	b.problems(login, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+
+	b.protected(http.MethodGet, "/auth/session", &Operation{
+		OperationID: "getSession",
+		Summary:     "Describe the token used for the request",
+		Tags:        []string{"auth"},
+		Responses:   b.ok("The session", model.SessionResponse{}),
+	})
+
+	change := &Operation{
+		OperationID: "changePassword",
+		Summary:     "Change the password of the authenticated user",
+		Description: "Also accepts the token issued for an expired password. Every " +
+			"other session of the user is revoked.",
+		Tags:        []string{"me"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		RequestBody: b.json(model.ChangePasswordRequest{}),
+		Responses:   b.ok("A new token", model.ChangePasswordResponse{}),
+	}
+	b.add(http.MethodPost, "/users/me/password", change)
+	b.problems(change, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+}
+
+func (b *builder) webhooks() {
+	b.admin(http.MethodGet, "/webhooks", &Operation{
+		OperationID: "listWebhooks",
+		Summary:     "List webhook subscriptions",
+		Tags:        []string{"webhooks"},
+		Responses:   b.ok("The subscriptions", []model.WebhookResponse{}),
+	})
+	create := &Operation{
+		OperationID: "createWebhook",
+		Summary:     "Subscribe to user events",
+		Description: "The signing secret is generated when empty and is only returned here.",
+		Tags:        []string{"webhooks"},
+		RequestBody: b.json(model.CreateWebhookRequest{}),
+		Responses:   b.respond(http.StatusCreated, "The subscription", jsonType, b.gen.ref(model.WebhookResponse{})),
+	}
+	b.admin(http.MethodPost, "/webhooks", create)
+	b.problems(create, http.StatusBadRequest)
+
+	get := &Operation{
+		OperationID: "getWebhook",
+		Summary:     "Get a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Responses:   b.ok("The subscription", model.WebhookResponse{}),
+	}
+	b.admin(http.MethodGet, "/webhooks/{id:[0-9]+}", get)
+	b.problems(get, http.StatusBadRequest, http.StatusNotFound)
+	update := &Operation{
+		OperationID: "updateWebhook",
+		Summary:     "Replace a webhook subscription",
+		Tags:        []string{"webhooks"},
+		RequestBody: b.json(model.UpdateWebhookRequest{}),
+		Responses:   b.ok("The subscription", model.WebhookResponse{}),
+	}
+	b.admin(http.MethodPut, "/webhooks/{id:[0-9]+}", update)
+	b.problems(update, http.StatusBadRequest, http.StatusNotFound)
+	del := &Operation{
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Responses:   empty(http.StatusNoContent, "The subscription was deleted"),
+	}
+	b.admin(http.MethodDelete, "/webhooks/{id:[0-9]+}", del)
+	b.problems(del, http.StatusBadRequest, http.StatusNotFound)
+
+	deliveries := &Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "List the deliveries of a subscription",
+		Tags:        []string{"webhooks"},
+		Responses:   b.ok("The deliveries", []model.WebhookDelivery{}),
+	}
+	b.admin(http.MethodGet, "/webhooks/{id:[0-9]+}/deliveries", deliveries)
+	b.problems(deliveries, http.StatusBadRequest, http.StatusNotFound)
+	delivery := &Operation{
+		OperationID: "getWebhookDelivery",
+		Summary:     "Get a delivery with its attempt history",
+		Tags:        []string{"webhooks"},
+		Responses:   b.ok("The delivery", model.WebhookDeliveryResponse{}),
+	}
+	b.admin(http.MethodGet, "/webhooks/{id:[0-9]+}/deliveries/{deliveryID:[0-9]+}", delivery)
+	b.problems(delivery, http.StatusBadRequest, http.StatusNotFound)
+	redeliver := &Operation{
+		OperationID: "redeliverWebhook",
+		Summary:     "Queue a delivery to be sent again",
+		Tags:        []string{"webhooks"},
+		Responses:   b.respond(http.StatusAccepted, "The delivery was queued", jsonType, b.gen.ref(model.WebhookDelivery{})),
+	}
+	b.admin(http.MethodPost, "/webhooks/{id:[0-9]+}/deliveries/{deliveryID:[0-9]+}/redeliver", redeliver)
+	b.problems(redeliver, http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (b *builder) graphql() {
+	op := &Operation{
+		OperationID: "graphql",
+		Summary:     "Run a GraphQL query or mutation",
+		Description: "A bearer token is optional here: createUser needs none and " +
+			"every other field fails with code unauthorized without one. Errors " +
+			"carry the problem codes in their extensions.",
+		Tags:        []string{"graphql"},
+		Security:    []map[string][]string{{}, {"bearerAuth": {}}},
+		RequestBody: b.json(graphqlRequest{}),
+		Responses:   b.ok("The result, possibly with errors", graphqlResponse{}),
+	}
+	b.add(http.MethodPost, "/graphql", op)
+	b.problems(op, http.StatusUnauthorized)
+}
+
+func (b *builder) introspection() {
+	op := &Operation{
+		OperationID: "introspectToken",
+		Summary:     "Introspect an access token (RFC 7662)",
+		Tags:        []string{"auth"},
+		Security:    []map[string][]string{{"introspectionClient": {}}},
+		RequestBody: b.body("application/x-www-form-urlencoded", b.gen.ref(introspectionRequest{})),
+		Responses:   b.ok("The token state; inactive tokens only set active", introspect.Response{}),
+		EnabledBy:   "INTROSPECTION_CLIENTS",
+	}
+	b.merge(op, b.respond(http.StatusBadRequest, "No token was sent", jsonType, b.gen.ref(oauthError{})))
+	b.merge(op, b.respond(http.StatusUnauthorized, "Invalid client credentials", jsonType, b.gen.ref(oauthError{})))
+	b.add(http.MethodPost, "/oauth2/introspect", op)
+}
+
+func (b *builder) scim() {
+	base := scim.BasePath
+	add := func(method, path string, op *Operation, statuses ...int) {
+		op.Tags = []string{"scim"}
+		op.Security = []map[string][]string{{"scimToken": {}}}
+		op.EnabledBy = "SCIM_BEARER_TOKEN"
+		b.add(method, base+path, op)
+		b.scimErrors(op, append(statuses, http.StatusUnauthorized, http.StatusInternalServerError)...)
+	}
+	respond := func(status int, description string, v any) map[string]*Response {
+		return b.respond(status, description, scim.ContentType, b.gen.ref(v))
+	}
+	body := func(v any) *RequestBody {
+		return b.body(scim.ContentType, b.gen.ref(v))
+	}
+
+	add(http.MethodGet, "/Users", &Operation{
+		OperationID: "scimListUsers",
+		Summary:     "List users",
+		Parameters: []*Parameter{
+			{Name: "filter", In: "query", Description: "SCIM filter on userName, emails.value, active or id.", Schema: &Schema{Type: "string"}},
+			{Name: "startIndex", In: "query", Schema: &Schema{Type: "integer"}},
+			{Name: "count", In: "query", Schema: &Schema{Type: "integer"}},
+		},
+		Responses: respond(http.StatusOK, "One page of users", scim.ListResponse[scim.User]{}),
+	}, http.StatusBadRequest)
+	create := &Operation{
+		OperationID: "scimCreateUser",
+		Summary:     "Provision a user",
+		Description: "A random password is generated when none is sent.",
+		RequestBody: body(scim.User{}),
+		Responses:   respond(http.StatusCreated, "The user", scim.User{}),
+	}
+	create.Responses["201"].Headers = map[string]*Header{"Location": {Schema: &Schema{Type: "string"}}}
+	add(http.MethodPost, "/Users", create, http.StatusBadRequest, http.StatusConflict)
+	add(http.MethodGet, "/Users/{id}", &Operation{
+		OperationID: "scimGetUser",
+		Summary:     "Get a user",
+		Responses:   respond(http.StatusOK, "The user", scim.User{}),
+	}, http.StatusNotFound)
+	add(http.MethodPut, "/Users/{id}", &Operation{
+		OperationID: "scimReplaceUser",
+		Summary:     "Replace a user",
+		RequestBody: body(scim.User{}),
+		Responses:   respond(http.StatusOK, "The user", scim.User{}),
+	}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)
+	add(http.MethodPatch, "/Users/{id}", &Operation{
+		OperationID: "scimPatchUser",
+		Summary:     "Patch a user",
+		RequestBody: body(scim.PatchRequest{}),
+		Responses:   respond(http.StatusOK, "The user", scim.User{}),
+	}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)
+	add(http.MethodDelete, "/Users/{id}", &Operation{
+		OperationID: "scimDeleteUser",
+		Summary:     "Deprovision a user",
+		Responses:   empty(http.StatusNoContent, "The user was deleted"),
+	}, http.StatusNotFound)
+	add(http.MethodGet, "/ServiceProviderConfig", &Operation{
+		OperationID: "scimServiceProviderConfig",
+		Summary:     "Describe the supported SCIM features",
+		Responses:   respond(http.StatusOK, "The configuration", scim.ServiceProviderConfig{}),
+	})
+	add(http.MethodGet, "/ResourceTypes", &Operation{
+		OperationID: "scimResourceTypes",
+		Summary:     "List the resource types",
+		Responses:   respond(http.StatusOK, "The resource types", scim.ListResponse[scim.ResourceType]{}),
+	})
+	add(http.MethodGet, "/Schemas", &Operation{
+		OperationID: "scimSchemas",
+		Summary:     "List the resource schemas",
+		Responses:   respond(http.StatusOK, "The schemas", scim.ListResponse[scim.SchemaDefinition]{}),
+	})
+}
+
+func (b *builder) docs() {
+	b.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Tags:        []string{"docs"},
+		Responses:   b.respond(http.StatusOK, "The OpenAPI document", jsonType, &Schema{Type: "object"}),
+	})
+	b.add(http.MethodGet, "/docs", &Operation{
+		OperationID: "getDocs",
+		Summary:     "Interactive documentation for this document",
+		Tags:        []string{"docs"},
+		Responses:   b.respond(http.StatusOK, "The docs page", "text/html", &Schema{Type: "string"}),
+	})
+	asset := &Operation{
+		OperationID: "getDocsAsset",
+		Summary:     "A script or stylesheet of the docs page",
+		Tags:        []string{"docs"},
+		Responses:   empty(http.StatusOK, "The file"),
+	}
+	b.add(http.MethodGet, "/docs/{asset}", asset)
+	b.merge(asset, empty(http.StatusNotFound, "No such file"))
+}
+
+// pathTemplate turns a mux route like /users/{id:[0-9]+} into the OpenAPI
+// path /users/{id} and its parameters. Numeric ids are integers; other
+// patterns are kept as a pattern on a string.
+func pathTemplate(route string) (string, []*Parameter) {
+	var params []*Parameter
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name, pattern, _ := strings.Cut(segment[1:len(segment)-1], ":")
+		schema := &Schema{Type: "string"}
+		switch pattern {
+		case "":
+		case "[0-9]+":
+			schema = &Schema{Type: "integer", Minimum: floatPtr(0)}
+		default:
+			schema.Pattern = "^(?:" + pattern + ")$"
+		}
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func problemName(status int) string {
+	return strings.ReplaceAll(http.StatusText(status), " ", "")
+}
+
+func field(v any, name string) string {
+	return reflect.TypeOf(v).Name() + "." + name
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func floatPtr(n float64) *float64 {
+	return &n
+}
\ No newline at end of file
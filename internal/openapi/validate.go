@@ -0,0 +1,273 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"user-management/internal/errors"
+	"user-management/internal/validation"
+)
+
+// Find returns the operation for a method and a mux path template such as
+// /users/{id:[0-9]+}, or nil.
+func (d *Document) Find(method, template string) *Operation {
+	path, _ := pathTemplate(template)
+	return d.operation(method + " " + path)
+}
+
+// ValidateParams checks the query and path parameters of op. Query
+// parameters the operation does not declare are ignored.
+func (d *Document) ValidateParams(op *Operation, query url.Values, vars map[string]string) []errors.FieldViolation {
+	var violations []errors.FieldViolation
+	for _, p := range op.Parameters {
+		p = d.parameter(p)
+		var (
+			value string
+			ok    bool
+		)
+		switch p.In {
+		case "query":
+			ok = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "path":
+			value, ok = vars[p.Name]
+		default:
+			continue
+		}
+		if !ok {
+			if p.Required {
+				violations = append(violations, violation(p.Name, "required", "is required"))
+			}
+			continue
+		}
+		v, err := d.coerce(p.Schema, value)
+		if err != nil {
+			violations = append(violations, violation(p.Name, "invalid_type", err.Error()))
+			continue
+		}
+		violations = append(violations, d.Validate(p.Schema, v, p.Name)...)
+	}
+	return violations
+}
+
+// ValidateBody checks a request body against op. Bodies of media types
+// other than JSON are left to the handler. The error is set when the body
+// is not JSON at all.
+func (d *Document) ValidateBody(op *Operation, contentType string, body []byte) ([]errors.FieldViolation, error) {
+	if op.RequestBody == nil {
+		return nil, nil
+	}
+	schema, ok := jsonSchema(op.RequestBody.Content, contentType)
+	if !ok {
+		return nil, nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return nil, fmt.Errorf("request body is required")
+		}
+		return nil, nil
+	}
+	v, err := decode(body)
+	if err != nil {
+		return nil, err
+	}
+	return d.Validate(schema, v, ""), nil
+}
+
+// ValidateResponse checks a JSON response body against what op documents
+// for status. Undocumented statuses are reported as a violation too.
+func (d *Document) ValidateResponse(op *Operation, status int, contentType string, body []byte) []errors.FieldViolation {
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return []errors.FieldViolation{violation("status", "undocumented", fmt.Sprintf("%d is not a documented status", status))}
+	}
+	if response.Ref != "" {
+		response = d.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+	}
+	schema, ok := jsonSchema(response.Content, contentType)
+	if !ok {
+		return nil
+	}
+	v, err := decode(body)
+	if err != nil {
+		return []errors.FieldViolation{violation("body", "invalid_json", err.Error())}
+	}
+	return d.Validate(schema, v, "")
+}
+
+// Validate checks a decoded JSON value against s. Field names are paths
+// like operations[0].user.email. Of the formats, which JSON Schema 2020-12
+// leaves as annotations, only email is asserted, as the service would.
+func (d *Document) Validate(s *Schema, v any, field string) []errors.FieldViolation {
+	if s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if v == nil {
+		if s.Type == "" || s.Nullable {
+			return nil
+		}
+		return []errors.FieldViolation{violation(field, "invalid_type", "must be of type "+s.Type)}
+	}
+	if s.Type != "" && !hasType(v, s.Type) {
+		return []errors.FieldViolation{violation(field, "invalid_type", "must be of type "+s.Type)}
+	}
+	var violations []errors.FieldViolation
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, violation(join(field, name), "required", "is required"))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			switch {
+			case ok:
+				violations = append(violations, d.Validate(prop, v[name], join(field, name))...)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				violations = append(violations, violation(join(field, name), "unknown_field", "is not a recognized field"))
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				violations = append(violations, d.Validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		switch {
+		case n == 0 && s.MinLength != nil && *s.MinLength == 1:
+			// minLength 1 is how the generator marks a required string
+			violations = append(violations, violation(field, "required", "is required"))
+		case s.MinLength != nil && n < *s.MinLength:
+			violations = append(violations, violation(field, "too_short", fmt.Sprintf("must be at least %d characters", *s.MinLength)))
+		case s.MaxLength != nil && n > *s.MaxLength:
+			violations = append(violations, violation(field, "too_long", fmt.Sprintf("must be at most %d characters", *s.MaxLength)))
+		case s.Format == "email" && n > 0 && !validation.IsEmail(v):
+			violations = append(violations, violation(field, "invalid_email", "must be a valid email address"))
+		case s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(v):
+			violations = append(violations, violation(field, "invalid_format", "does not match "+s.Pattern))
+		case len(s.Enum) > 0 && !contains(s.Enum, v):
+			violations = append(violations, violation(field, "invalid_value", "must be one of "+strings.Join(s.Enum, ", ")))
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		switch {
+		case s.Minimum != nil && f < *s.Minimum:
+			violations = append(violations, violation(field, "too_small", fmt.Sprintf("must be at least %v", *s.Minimum)))
+		case s.Maximum != nil && f > *s.Maximum:
+			violations = append(violations, violation(field, "too_large", fmt.Sprintf("must be at most %v", *s.Maximum)))
+		}
+	}
+	return violations
+}
+
+// coerce parses a query or path value as the type of s.
+func (d *Document) coerce(s *Schema, value string) (any, error) {
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return json.Number(value), nil
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return json.Number(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+func (d *Document) parameter(p *Parameter) *Parameter {
+	if p.Ref == "" {
+		return p
+	}
+	return d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+}
+
+// jsonSchema returns the schema for contentType when it is a JSON media
+// type the content lists. A missing content type is taken as JSON.
+func jsonSchema(content map[string]*MediaType, contentType string) (*Schema, bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = jsonType
+	}
+	if mediaType != jsonType && !strings.HasSuffix(mediaType, "+json") {
+		return nil, false
+	}
+	m, ok := content[mediaType]
+	if !ok || m.Schema == nil {
+		return nil, false
+	}
+	return m.Schema, true
+}
+
+func decode(body []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid json")
+	}
+	return v, nil
+}
+
+func hasType(v any, typ string) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return typ == "number"
+	}
+	return false
+}
+
+func violation(field, code, message string) errors.FieldViolation {
+	return errors.FieldViolation{Field: field, Code: code, Message: message}
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
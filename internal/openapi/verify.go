@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Verify compares the operations of doc with the routes registered on
+// router and reports every difference. Operations marked x-enabled-by may
+// be missing from the router, since their routes are optional.
+func Verify(router *mux.Router, doc *Document) error {
+	routes := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// subrouter prefixes match no request themselves
+			return nil
+		}
+		path, _ := pathTemplate(template)
+		for _, method := range methods {
+			routes[method+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var undocumented, unrouted []string
+	for op := range routes {
+		if doc.operation(op) == nil {
+			undocumented = append(undocumented, op)
+		}
+	}
+	for _, op := range doc.Operations() {
+		if !routes[op] && doc.operation(op).EnabledBy == "" {
+			unrouted = append(unrouted, op)
+		}
+	}
+	if len(undocumented) == 0 && len(unrouted) == 0 {
+		return nil
+	}
+	sort.Strings(undocumented)
+	var problems []string
+	if len(undocumented) > 0 {
+		problems = append(problems, "routes missing from the spec: "+strings.Join(undocumented, ", "))
+	}
+	if len(unrouted) > 0 {
+		problems = append(problems, "spec operations with no route: "+strings.Join(unrouted, ", "))
+	}
+	return fmt.Errorf("openapi: %s", strings.Join(problems, "; "))
+}
+
+// operation looks up an operation by "METHOD /path".
+func (d *Document) operation(op string) *Operation {
+	method, path, _ := strings.Cut(op, " ")
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
\ No newline at end of file
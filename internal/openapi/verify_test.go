@@ -0,0 +1,57 @@
+package openapi_test
+
+import (
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/middleware"
+	"user-management/internal/openapi"
+	"user-management/internal/repository"
+	"user-management/internal/server"
+	"user-management/internal/service"
+	"user-management/internal/stream"
+
+	"github.com/gorilla/mux"
+)
+
+// TestSpecMatchesRoutes builds the router main serves and checks it against
+// the spec, once with every optional feature enabled and once with none.
+func TestSpecMatchesRoutes(t *testing.T) {
+	optional := &config.Config{
+		SCIMToken:            "scim-token",
+		IntrospectionClients: map[string]string{"orders": "secret"},
+		WebhooksAdminToken:   "admin-token",
+	}
+	for name, cfg := range map[string]*config.Config{"all features": optional, "defaults": {}} {
+		t.Run(name, func(t *testing.T) {
+			cfg.Stream = config.StreamConfig{ReplaySize: 10, SubscriberBuffer: 1, Heartbeat: time.Second}
+			spec := openapi.Spec()
+			// the routes are only walked, so the handlers need no working storage
+			router, err := server.NewRouter(cfg, spec, server.Deps{
+				Users:       service.NewUserService(cfg, repository.NewMemoryRepository(), nil, nil),
+				Webhooks:    service.NewWebhookService(nil, cfg.Webhooks),
+				Idempotency: middleware.NewIdempotency(nil, time.Hour),
+				Broker:      stream.NewBroker(cfg.Stream),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := openapi.Verify(router, spec); err != nil {
+				t.Error(err)
+			}
+			if cfg != optional {
+				return
+			}
+			// Verify lets optional routes be missing; here they are all enabled
+			routed := 0
+			router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+				methods, _ := route.GetMethods()
+				routed += len(methods)
+				return nil
+			})
+			if want := len(spec.Operations()); routed != want {
+				t.Errorf("router has %d operations with every feature enabled, spec has %d", routed, want)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,97 @@
+// Package problem renders errors as RFC 9457 application/problem+json documents.
+package problem
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"user-management/internal/auth"
+	"user-management/internal/errors"
+)
+
+const ContentType = "application/problem+json"
+
+// Machine-readable codes. Clients should branch on these, not on detail text.
+const (
+	CodeValidationFailed     = "validation_failed"
+	CodeInvalidJSON          = "invalid_json"
+	CodeInvalidID            = "invalid_id"
+	CodeNotFound             = "not_found"
+	CodeDuplicate            = "duplicate"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodePasswordExpired      = "password_expired"
+	CodeInternal             = "internal_error"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeBatchAborted         = "batch_aborted"
+	CodeInvalidImport        = "invalid_import"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+
+	CodeInvalidIdempotencyKey = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"
+	CodeIdempotencyInProgress = "idempotency_in_progress"
+)
+
+type Problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail,omitempty"`
+	Instance string                  `json:"instance,omitempty"`
+	Code     string                  `json:"code"`
+	Errors   []errors.FieldViolation `json:"errors,omitempty"`
+}
+
+func New(r *http.Request, status int, code, detail string) *Problem {
+	return &Problem{
+		Type:     "/problems/" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+}
+
+func (p *Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Write renders a problem with no field details.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	New(r, status, code, detail).Write(w)
+}
+
+// WriteError maps err to a problem. Wrapped errors are unwrapped with
+// errors.As; anything unrecognized is logged and reported as a bare 500.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	FromError(r, err).Write(w)
+}
+
+func FromError(r *http.Request, err error) *Problem {
+	var (
+		validation *errors.ValidationError
+		notFound   *errors.NotFoundError
+		duplicate  *errors.DuplicateError
+	)
+	switch {
+	case stderrors.As(err, &validation):
+		p := New(r, http.StatusBadRequest, CodeValidationFailed, "request failed validation")
+		p.Errors = validation.Violations
+		return p
+	case stderrors.As(err, &notFound):
+		return New(r, http.StatusNotFound, CodeNotFound, notFound.Error())
+	case stderrors.As(err, &duplicate):
+		return New(r, http.StatusConflict, CodeDuplicate, duplicate.Error())
+	case stderrors.Is(err, auth.ErrInvalidCredentials):
+		return New(r, http.StatusUnauthorized, CodeInvalidCredentials, err.Error())
+	case stderrors.Is(err, auth.ErrSessionRevoked):
+		return New(r, http.StatusUnauthorized, CodeUnauthorized, "invalid or expired token")
+	default:
+		slog.Error("unhandled error", "error", err, "path", r.URL.Path)
+		return New(r, http.StatusInternalServerError, CodeInternal, "an unexpected error occurred")
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+	"user-management/internal/model"
+)
+
+type IdempotencyRepo interface {
+	// ClaimIdempotencyKey stores rec as in progress unless an unexpired record
+	// for the same scope and key exists, in which case that record is
+	// returned and claimed is false.
+	ClaimIdempotencyKey(rec model.IdempotencyRecord) (existing *model.IdempotencyRecord, claimed bool, err error)
+	CompleteIdempotencyKey(rec model.IdempotencyRecord) error
+	// ReleaseIdempotencyKey forgets an in-progress key so the request can be retried.
+	ReleaseIdempotencyKey(scope, key string) error
+	PurgeExpiredIdempotencyKeys() (int64, error)
+}
+
+func (r *PostgresRepository) ClaimIdempotencyKey(rec model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error) {
+	// an expired record is replaced as if it were not there
+	if _, err := r.db.Exec(`delete from idempotency_keys where scope=$1 and key=$2 and expires_at<=CURRENT_TIMESTAMP`, rec.Scope, rec.Key); err != nil {
+		slog.Error("unable to clear expired idempotency key", "error", err, "idempotency_key", rec.Key)
+		return nil, false, err
+	}
+	query := `insert into idempotency_keys (scope,key,fingerprint,expires_at) values($1,$2,$3,$4)
+		on conflict (scope,key) do nothing`
+	result, err := r.db.Exec(query, rec.Scope, rec.Key, rec.Fingerprint, rec.ExpiresAt)
+	if err != nil {
+		slog.Error("unable to claim idempotency key", "error", err, "idempotency_key", rec.Key)
+		return nil, false, err
+	}
+	if n, _ := result.RowsAffected(); n == 1 {
+		return nil, true, nil
+	}
+
+	var existing model.IdempotencyRecord
+	query = `select scope,key,fingerprint,completed,coalesce(status_code,0),coalesce(content_type,''),coalesce(location,''),
+		coalesce(body,''::bytea),expires_at from idempotency_keys where scope=$1 and key=$2`
+	err = r.db.QueryRow(query, rec.Scope, rec.Key).Scan(&existing.Scope, &existing.Key, &existing.Fingerprint, &existing.Completed,
+		&existing.StatusCode, &existing.ContentType, &existing.Location, &existing.Body, &existing.ExpiresAt)
+	if err == sql.ErrNoRows {
+		// released between the insert and the select; the client can retry
+		existing = model.IdempotencyRecord{Scope: rec.Scope, Key: rec.Key, Fingerprint: rec.Fingerprint}
+		return &existing, false, nil
+	}
+	if err != nil {
+		slog.Error("unable to load idempotency key", "error", err, "idempotency_key", rec.Key)
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+func (r *PostgresRepository) CompleteIdempotencyKey(rec model.IdempotencyRecord) error {
+	query := `update idempotency_keys set completed=true,status_code=$1,content_type=$2,location=$3,body=$4
+		where scope=$5 and key=$6`
+	_, err := r.db.Exec(query, rec.StatusCode, rec.ContentType, rec.Location, rec.Body, rec.Scope, rec.Key)
+	if err != nil {
+		slog.Error("unable to store idempotent response", "error", err, "idempotency_key", rec.Key)
+	}
+	return err
+}
+
+func (r *PostgresRepository) ReleaseIdempotencyKey(scope, key string) error {
+	_, err := r.db.Exec(`delete from idempotency_keys where scope=$1 and key=$2 and not completed`, scope, key)
+	if err != nil {
+		slog.Error("unable to release idempotency key", "error", err, "idempotency_key", key)
+	}
+	return err
+}
+
+func (r *PostgresRepository) PurgeExpiredIdempotencyKeys() (int64, error) {
+	result, err := r.db.Exec(`delete from idempotency_keys where expires_at<=$1`, time.Now())
+	if err != nil {
+		slog.Error("unable to purge expired idempotency keys", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
\ No newline at end of file
@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"user-management/internal/errors"
+	"user-management/internal/events"
+	"user-management/internal/model"
+)
+
+// MemoryRepository is an in-memory Store for tests and local runs. It keeps
+// the same case-insensitive uniqueness rules as the Postgres indexes, and
+// WithTx restores a snapshot when fn fails so rollback paths can be exercised.
+type MemoryRepository struct {
+	mu      sync.Mutex
+	txMu    sync.Mutex
+	users   map[int]model.User
+	history map[int][]passwordEntry
+	nextID  int
+	outbox  []outboxEntry
+}
+
+var _ Store = (*MemoryRepository)(nil)
+
+type outboxEntry struct {
+	event     events.Event
+	delivered bool
+	failed    bool
+	attempts  int
+	lastError string
+}
+
+type passwordEntry struct {
+	hash      string
+	createdAt time.Time
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:   map[int]model.User{},
+		history: map[int][]passwordEntry{},
+		nextID:  1,
+	}
+}
+
+// WithTx serializes transactions against each other. Calls made outside a
+// transaction are not isolated from one that is running.
+func (m *MemoryRepository) WithTx(ctx context.Context, fn func(Repos) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.Lock()
+	users := make(map[int]model.User, len(m.users))
+	for id, u := range m.users {
+		users[id] = u
+	}
+	history := make(map[int][]passwordEntry, len(m.history))
+	for id, h := range m.history {
+		history[id] = append([]passwordEntry(nil), h...)
+	}
+	nextID := m.nextID
+	outbox := append([]outboxEntry(nil), m.outbox...)
+	m.mu.Unlock()
+
+	if err := fn(Repos{Users: m, Outbox: m}); err != nil {
+		m.mu.Lock()
+		m.users, m.history, m.nextID, m.outbox = users, history, nextID, outbox
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *MemoryRepository) GetAll(filter model.UserFilter) ([]model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := make([]model.User, 0, len(m.users))
+	for _, u := range m.users {
+		if !filter.Matches(u) {
+			continue
+		}
+		u.Password = ""
+		u.PasswordChangedAt = time.Time{}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// StreamAll works on a copy, so fn may call back into the repository.
+func (m *MemoryRepository) StreamAll(filter model.UserFilter, fn func(model.User) error) error {
+	users, err := m.GetAll(filter)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) GetByID(id int) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return nil, errors.NewNotFoundError(id, "no user with that id")
+	}
+	return &u, nil
+}
+
+func (m *MemoryRepository) GetByIDs(ids []int) ([]model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []model.User
+	for _, id := range ids {
+		if u, ok := m.users[id]; ok {
+			u.Password = ""
+			u.PasswordChangedAt = time.Time{}
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (m *MemoryRepository) GetByEmail(email string) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, email) {
+			return &u, nil
+		}
+	}
+	return nil, errors.NewNotFoundError(email, "no user with that email")
+}
+
+func (m *MemoryRepository) Create(user *model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkUnique(0, user); err != nil {
+		return err
+	}
+	user.ID = m.nextID
+	user.PasswordChangedAt = time.Now()
+	m.nextID++
+	m.users[user.ID] = *user
+	return nil
+}
+
+func (m *MemoryRepository) Update(id int, user model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.users[id]
+	if !ok {
+		return errors.NewNotFoundError(id, "user not found")
+	}
+	if err := m.checkUnique(id, &user); err != nil {
+		return err
+	}
+	existing.Username, existing.Email, existing.Password, existing.Name = user.Username, user.Email, user.Password, user.Name
+	existing.GivenName, existing.FamilyName = user.GivenName, user.FamilyName
+	m.users[id] = existing
+	return nil
+}
+
+func (m *MemoryRepository) UpdatePassword(id int, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return errors.NewNotFoundError(id, "no user with the given id")
+	}
+	u.Password = password
+	m.users[id] = u
+	return nil
+}
+
+func (m *MemoryRepository) SetActive(id int, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return errors.NewNotFoundError(id, "no user with the given id")
+	}
+	u.IsActive = active
+	m.users[id] = u
+	return nil
+}
+
+func (m *MemoryRepository) GetPasswordHistory(id int, limit int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := m.history[id]
+	var hashes []string
+	for i := len(entries) - 1; i >= 0 && len(hashes) < limit; i-- {
+		hashes = append(hashes, entries[i].hash)
+	}
+	return hashes, nil
+}
+
+func (m *MemoryRepository) RecordPasswordChange(id int, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return errors.NewNotFoundError(id, "no user with the given id")
+	}
+	now := time.Now()
+	m.history[id] = append(m.history[id], passwordEntry{hash: password, createdAt: now})
+	u.PasswordChangedAt = now
+	m.users[id] = u
+	return nil
+}
+
+func (m *MemoryRepository) Delete(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[id]; !ok {
+		return errors.NewNotFoundError(id, "no user with the given id")
+	}
+	delete(m.users, id)
+	delete(m.history, id)
+	return nil
+}
+
+func (m *MemoryRepository) ExistsByEmail(email string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryRepository) ExistsByID(id int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.users[id]
+	return ok
+}
+
+func (m *MemoryRepository) ExistsByUsername(username string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if strings.EqualFold(u.Username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnique mirrors the lower(email) and lower(username) unique indexes.
+// Callers must hold m.mu.
+func (m *MemoryRepository) checkUnique(selfID int, user *model.User) error {
+	for id, u := range m.users {
+		if id == selfID {
+			continue
+		}
+		if strings.EqualFold(u.Email, user.Email) {
+			return errors.NewDuplicateError("email", user.Email)
+		}
+		if strings.EqualFold(u.Username, user.Username) {
+			return errors.NewDuplicateError("username", user.Username)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) Append(e *events.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.ID = int64(len(m.outbox) + 1)
+	m.outbox = append(m.outbox, outboxEntry{event: *e})
+	return nil
+}
+
+func (m *MemoryRepository) FetchPending(limit int) ([]events.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pending []events.Event
+	for _, entry := range m.outbox {
+		if len(pending) == limit {
+			break
+		}
+		if !entry.delivered && !entry.failed {
+			e := entry.event
+			e.Attempts = entry.attempts
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (m *MemoryRepository) MarkDelivered(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &m.outbox[id-1]
+	entry.delivered = true
+	entry.attempts++
+	entry.lastError = ""
+	return nil
+}
+
+func (m *MemoryRepository) MarkFailed(id int64, reason error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &m.outbox[id-1]
+	entry.attempts++
+	entry.lastError = reason.Error()
+	return nil
+}
+
+func (m *MemoryRepository) MarkDead(id int64, reason error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &m.outbox[id-1]
+	entry.failed = true
+	entry.attempts++
+	entry.lastError = reason.Error()
+	return nil
+}
+
+func (m *MemoryRepository) CreateMany(users []*model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// check the whole batch first so a failure inserts nothing
+	for i, user := range users {
+		if err := m.checkUnique(0, user); err != nil {
+			return err
+		}
+		for _, other := range users[:i] {
+			if strings.EqualFold(other.Email, user.Email) {
+				return errors.NewDuplicateError("email", user.Email)
+			}
+			if strings.EqualFold(other.Username, user.Username) {
+				return errors.NewDuplicateError("username", user.Username)
+			}
+		}
+	}
+	now := time.Now()
+	for _, user := range users {
+		user.ID = m.nextID
+		user.IsActive = true
+		user.PasswordChangedAt = now
+		m.nextID++
+		m.users[user.ID] = *user
+		m.history[user.ID] = append(m.history[user.ID], passwordEntry{hash: user.Password, createdAt: now})
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ExistingEmails(emails []string) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := map[string]bool{}
+	for _, email := range emails {
+		for _, u := range m.users {
+			if strings.EqualFold(u.Email, email) {
+				found[strings.ToLower(email)] = true
+			}
+		}
+	}
+	return found, nil
+}
+
+func (m *MemoryRepository) ExistingUsernames(usernames []string) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := map[string]bool{}
+	for _, username := range usernames {
+		for _, u := range m.users {
+			if strings.EqualFold(u.Username, username) {
+				found[strings.ToLower(username)] = true
+			}
+		}
+	}
+	return found, nil
+}
\ No newline at end of file
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"log/slog"
+	"user-management/internal/events"
+)
+
+// OutboxRepo stores domain events. Append is called inside the same
+// transaction as the change the event describes.
+type OutboxRepo interface {
+	events.Outbox
+	Append(e *events.Event) error
+}
+
+func (r *PostgresRepository) Append(e *events.Event) error {
+	query := `insert into outbox (user_id,event_type,payload,occurred_at) values($1,$2,$3,$4) returning id`
+	err := r.db.QueryRow(query, e.UserID, e.Type, []byte(e.Payload), e.OccurredAt).Scan(&e.ID)
+	if err != nil {
+		slog.Error("failed to append outbox event", "error", err, "event_type", e.Type, "user_id", e.UserID)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRepository) FetchPending(limit int) ([]events.Event, error) {
+	query := `select id,user_id,event_type,payload,occurred_at,attempts from outbox where delivered_at is null and failed_at is null order by id limit $1`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		slog.Error("failed to query pending outbox events", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var pending []events.Event
+	for rows.Next() {
+		var e events.Event
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &payload, &e.OccurredAt, &e.Attempts); err != nil {
+			slog.Error("failed to scan outbox row", "error", err)
+			return nil, err
+		}
+		e.Payload = payload
+		pending = append(pending, e)
+	}
+	return pending, rows.Err()
+}
+
+func (r *PostgresRepository) MarkDelivered(id int64) error {
+	_, err := r.db.Exec(`update outbox set delivered_at=CURRENT_TIMESTAMP, attempts=attempts+1, last_error=null where id=$1`, id)
+	return err
+}
+
+func (r *PostgresRepository) MarkFailed(id int64, reason error) error {
+	_, err := r.db.Exec(`update outbox set attempts=attempts+1, last_error=$2 where id=$1`, id, reason.Error())
+	return err
+}
+
+func (r *PostgresRepository) MarkDead(id int64, reason error) error {
+	_, err := r.db.Exec(`update outbox set attempts=attempts+1, last_error=$2, failed_at=CURRENT_TIMESTAMP where id=$1`, id, reason.Error())
+	return err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SQLSTATEs after which the whole transaction can safely be run again.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+const maxTxAttempts = 3
+
+// Repos groups the repositories that share one transaction.
+type Repos struct {
+	Users  UserRepo
+	Outbox OutboxRepo
+}
+
+// UnitOfWork runs fn with repositories bound to a single transaction. The
+// transaction commits when fn returns nil and rolls back otherwise. fn may be
+// called more than once when the database asks for a retry, so it must not
+// have side effects outside the repositories it is given.
+type UnitOfWork interface {
+	WithTx(ctx context.Context, fn func(Repos) error) error
+}
+
+// dbtx is the subset of *sql.DB and *sql.Tx the repositories use.
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func (r *PostgresRepository) WithTx(ctx context.Context, fn func(Repos) error) error {
+	// already inside a transaction: join it
+	if r.conn == nil {
+		return fn(Repos{Users: r, Outbox: r})
+	}
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = r.runTx(ctx, fn)
+		if !retryable(err) {
+			return err
+		}
+		slog.Warn("transaction conflict, retrying", "error", err, "attempt", attempt)
+		time.Sleep(time.Duration(attempt) * 20 * time.Millisecond)
+	}
+	return fmt.Errorf("transaction failed after %d attempts %w", maxTxAttempts, err)
+}
+
+func (r *PostgresRepository) runTx(ctx context.Context, fn func(Repos) error) error {
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		slog.Error("unable to begin transaction", "error", err)
+		return err
+	}
+	txRepo := &PostgresRepository{db: tx}
+	if err := fn(Repos{Users: txRepo, Outbox: txRepo}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			slog.Error("transaction rollback failed", "error", rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("transaction commit failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !stderrors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"user-management/internal/model"
+
+	"github.com/lib/pq"
+)
+
+// createManyChunk keeps each insert well below Postgres' 65535 parameter limit.
+const createManyChunk = 1000
+
+func (r *PostgresRepository) CreateMany(users []*model.User) error {
+	for start := 0; start < len(users); start += createManyChunk {
+		end := min(start+createManyChunk, len(users))
+		if err := r.createChunk(users[start:end]); err != nil {
+			return err
+		}
+	}
+	slog.Info("users created in bulk", "count", len(users))
+	return nil
+}
+
+func (r *PostgresRepository) createChunk(users []*model.User) error {
+	var values strings.Builder
+	args := make([]any, 0, len(users)*6)
+	byEmail := make(map[string]*model.User, len(users))
+	for i, u := range users {
+		if i > 0 {
+			values.WriteString(",")
+		}
+		fmt.Fprintf(&values, "($%d,$%d,$%d,$%d,$%d,$%d)", i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6)
+		args = append(args, u.Username, u.Email, u.Password, u.Name, u.GivenName, u.FamilyName)
+		byEmail[strings.ToLower(u.Email)] = u
+	}
+	// one round trip for the users and their history rows
+	query := `with ins as (
+			insert into Users (username,email,password,name,given_name,family_name) values ` + values.String() + `
+			returning id,email,password,isactive,password_changed_at
+		), hist as (
+			insert into password_history (user_id,password) select id,password from ins
+		)
+		select id,email,isactive,password_changed_at from ins`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		if dup := duplicateError(err, duplicateFromDetail(err)); dup != nil {
+			slog.Warn("bulk create rejected by unique index", "error", err)
+			return dup
+		}
+		slog.Error("failed to bulk create users", "error", err, "count", len(users))
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var created model.User
+		if err := rows.Scan(&created.ID, &created.Email, &created.IsActive, &created.PasswordChangedAt); err != nil {
+			slog.Error("failed to scan bulk created user", "error", err)
+			return err
+		}
+		// rows come back in no guaranteed order; emails are unique
+		if u, ok := byEmail[strings.ToLower(created.Email)]; ok {
+			u.ID, u.IsActive, u.PasswordChangedAt = created.ID, created.IsActive, created.PasswordChangedAt
+		}
+	}
+	return rows.Err()
+}
+
+// duplicateFromDetail recovers the offending value from a unique violation
+// such as `Key (lower(email::text))=(a@b.c) already exists.`, since a
+// multi-row insert cannot tell which of its users caused it.
+func duplicateFromDetail(err error) *model.User {
+	var pqErr *pq.Error
+	if !stderrors.As(err, &pqErr) {
+		return &model.User{}
+	}
+	_, value, _ := strings.Cut(pqErr.Detail, ")=(")
+	value, _, _ = strings.Cut(value, ") already exists")
+	return &model.User{Email: value, Username: value}
+}
+
+func (r *PostgresRepository) GetByIDs(ids []int) ([]model.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.Query(`select id,username,email,name,given_name,family_name,isactive from Users where id=any($1)`, pq.Array(ids))
+	if err != nil {
+		slog.Error("failed to execute GetByIDs query", "error", err, "count", len(ids))
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]model.User, 0, len(ids))
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Name, &user.GivenName, &user.FamilyName, &user.IsActive); err != nil {
+			slog.Error("failed to scan user row", "error", err, "operation", "GetByIDs")
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *PostgresRepository) ExistingEmails(emails []string) (map[string]bool, error) {
+	return r.existing(`select lower(email) from Users where lower(email)=any($1)`, emails)
+}
+
+func (r *PostgresRepository) ExistingUsernames(usernames []string) (map[string]bool, error) {
+	return r.existing(`select lower(username) from Users where lower(username)=any($1)`, usernames)
+}
+
+func (r *PostgresRepository) existing(query string, values []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(values) == 0 {
+		return found, nil
+	}
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+	rows, err := r.db.Query(query, pq.Array(lowered))
+	if err != nil {
+		slog.Error("failed to check existing values", "error", err, "count", len(values))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		found[v] = true
+	}
+	return found, rows.Err()
+}
\ No newline at end of file
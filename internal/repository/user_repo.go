@@ -2,29 +2,72 @@ package repository
 
 import (
 	"database/sql"
+	stderrors "errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"user-management/internal/errors"
 	"user-management/internal/model"
+
+	"github.com/lib/pq"
 )
 
+// uniqueViolation is the SQLSTATE Postgres reports when a unique index rejects a write.
+const uniqueViolation = "23505"
+
 type UserRepo interface {
-	GetAll() ([]model.User, error)
+	GetAll(filter model.UserFilter) ([]model.User, error)
+	// StreamAll calls fn for every matching user in id order while reading
+	// from the cursor, so memory use does not grow with the result. Secret
+	// columns are not read. An error from fn stops the iteration.
+	StreamAll(filter model.UserFilter, fn func(model.User) error) error
 	GetByID(id int) (*model.User, error)
+	// GetByIDs returns the users that exist among ids, without secret
+	// columns, in no particular order.
+	GetByIDs(ids []int) ([]model.User, error)
 	GetByEmail(email string) (*model.User, error)
+	// Create inserts user as active or not according to IsActive.
 	Create(user *model.User) error
+	// CreateMany inserts users with their first password history entry,
+	// setting ID and IsActive on each. Any failure inserts none of them.
+	CreateMany(users []*model.User) error
 	Update(id int, user model.User) error
+	UpdatePassword(id int, password string) error
+	SetActive(id int, active bool) error
+	GetPasswordHistory(id int, limit int) ([]string, error)
+	RecordPasswordChange(id int, password string) error
 	Delete(id int) error
 	ExistsByEmail(email string) bool
 	ExistsByID(id int) bool
 	ExistsByUsername(username string) bool
+	// ExistingEmails and ExistingUsernames return which of the given values
+	// are taken, lowercased.
+	ExistingEmails(emails []string) (map[string]bool, error)
+	ExistingUsernames(usernames []string) (map[string]bool, error)
+}
+
+// Store is a UserRepo that can also run several calls in one transaction
+// and serves the outbox to the event dispatcher.
+type Store interface {
+	UserRepo
+	OutboxRepo
+	UnitOfWork
 }
 
 type PostgresRepository struct {
-	db *sql.DB
+	// db is the pool, or the open transaction for repositories handed out by WithTx
+	db dbtx
+	// conn is nil inside a transaction
+	conn *sql.DB
 }
 
-func NewPostgresRepository(connectionString string) (UserRepo, error) {
+var (
+	_ Store           = (*PostgresRepository)(nil)
+	_ WebhookRepo     = (*PostgresRepository)(nil)
+	_ IdempotencyRepo = (*PostgresRepository)(nil)
+)
+
+func NewPostgresRepository(connectionString string) (*PostgresRepository, error) {
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
 		slog.Error("unable to open db conection", "error", err, "driver", "postgres")
@@ -35,37 +78,54 @@ func NewPostgresRepository(connectionString string) (UserRepo, error) {
 		return nil, err
 	}
 	slog.Info("database conn established - ping success!")
-	return &PostgresRepository{db: db}, nil
+	return &PostgresRepository{db: db, conn: db}, nil
 }
 
-func (r *PostgresRepository) GetAll() ([]model.User, error) {
-	query := `select id,username,email,name,isactive from Users`
-	rows, err := r.db.Query(query)
+func (r *PostgresRepository) GetAll(filter model.UserFilter) ([]model.User, error) {
+	var users []model.User
+	err := r.StreamAll(filter, func(user model.User) error {
+		users = append(users, user)
+		return nil
+	})
 	if err != nil {
-		slog.Error("failed to execute GetAll query", "error", err)
 		return nil, err
 	}
+	slog.Debug("retrieved users", "count", len(users))
+	return users, nil
+
+}
+
+func (r *PostgresRepository) StreamAll(filter model.UserFilter, fn func(model.User) error) error {
+	query := `select id,username,email,name,given_name,family_name,isactive from Users
+		where ($1::boolean is null or isactive=$1)
+		and ($2='' or strpos(lower(username),lower($2))>0 or strpos(lower(email),lower($2))>0 or strpos(lower(coalesce(name,'')),lower($2))>0)
+		and id>$3
+		order by id`
+	rows, err := r.db.Query(query, filter.Active, filter.Search, filter.AfterID)
+	if err != nil {
+		slog.Error("failed to execute GetAll query", "error", err)
+		return err
+	}
 	defer rows.Close()
-	var users []model.User
 	for rows.Next() {
 		var user model.User
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Name, &user.IsActive)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Name, &user.GivenName, &user.FamilyName, &user.IsActive)
 		if err != nil {
 			slog.Error("failed to scan user row", "error", err, "operation", "GetAll")
-			return nil, err
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
 		}
-		users = append(users, user)
 	}
-	slog.Debug("retrieved users", "count", len(users))
-	return users, nil
-
+	return rows.Err()
 }
 
 func (r *PostgresRepository) GetByID(id int) (*model.User, error) {
-	query := `select id,username,email,password,name,isactive from Users where id=$1`
+	query := `select id,username,email,password,name,given_name,family_name,isactive,password_changed_at from Users where id=$1`
 	row := r.db.QueryRow(query, id)
 	var user model.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.IsActive)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.GivenName, &user.FamilyName, &user.IsActive, &user.PasswordChangedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			slog.Error("user not found", "user_id", id)
@@ -79,10 +139,10 @@ func (r *PostgresRepository) GetByID(id int) (*model.User, error) {
 }
 func (r *PostgresRepository) GetByEmail(email string) (*model.User, error) {
 	if r.ExistsByEmail(email) {
-		query := `select id,username,email,name,isactive,password from Users where email=$1`
+		query := `select id,username,email,name,given_name,family_name,isactive,password,password_changed_at from Users where lower(email)=lower($1)`
 		row := r.db.QueryRow(query, email)
 		var user model.User
-		err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Name, &user.IsActive, &user.Password)
+		err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Name, &user.GivenName, &user.FamilyName, &user.IsActive, &user.Password, &user.PasswordChangedAt)
 		if err != nil {
 			slog.Error("error while scanining user by email", "error", err, "user_email", email)
 			return nil, err
@@ -94,9 +154,13 @@ func (r *PostgresRepository) GetByEmail(email string) (*model.User, error) {
 
 }
 func (r *PostgresRepository) Create(user *model.User) error {
-	query := `insert into Users (username,email,password,name) values($1,$2,$3,$4) returning id`
-	err := r.db.QueryRow(query, user.Username, user.Email, user.Password, user.Name).Scan(&user.ID)
+	query := `insert into Users (username,email,password,name,given_name,family_name,isactive) values($1,$2,$3,$4,$5,$6,$7) returning id`
+	err := r.db.QueryRow(query, user.Username, user.Email, user.Password, user.Name, user.GivenName, user.FamilyName, user.IsActive).Scan(&user.ID)
 	if err != nil {
+		if dup := duplicateError(err, user); dup != nil {
+			slog.Warn("create rejected by unique index", "error", err, "user_email", user.Email)
+			return dup
+		}
 		slog.Error("failed to create user", "error", err, "user_id", user.ID, "user_email", user.Email)
 		return err
 	}
@@ -106,9 +170,13 @@ func (r *PostgresRepository) Create(user *model.User) error {
 func (r *PostgresRepository) Update(id int, user model.User) error {
 	//can optimize by calling existsbyid here clear redundant code.
 	if r.ExistsByID(id) {
-		query := `update Users set username=$1 ,email=$2,password=$3,updated_at=CURRENT_TIMESTAMP  where id=$4 `
-		result, err := r.db.Exec(query, user.Username, user.Email, user.Password, id)
+		query := `update Users set username=$1 ,email=$2,password=$3,name=$4,given_name=$5,family_name=$6,updated_at=CURRENT_TIMESTAMP  where id=$7 `
+		result, err := r.db.Exec(query, user.Username, user.Email, user.Password, user.Name, user.GivenName, user.FamilyName, id)
 		if err != nil {
+			if dup := duplicateError(err, &user); dup != nil {
+				slog.Warn("update rejected by unique index", "error", err, "user_id", id)
+				return dup
+			}
 			slog.Error("unable to execute update query", "error", err, "user_id", id)
 			return fmt.Errorf("unable to exec query %w", err)
 		}
@@ -126,6 +194,69 @@ func (r *PostgresRepository) Update(id int, user model.User) error {
 	slog.Info("user updated successfully", "user_id", id)
 	return errors.NewNotFoundError(id, "user not found")
 }
+func (r *PostgresRepository) UpdatePassword(id int, password string) error {
+	query := `update Users set password=$1,updated_at=CURRENT_TIMESTAMP where id=$2`
+	result, err := r.db.Exec(query, password, id)
+	if err != nil {
+		slog.Error("unable to execute password update query", "error", err, "user_id", id)
+		return fmt.Errorf("unable to exec query %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		slog.Error("unable to get rows affected", "error", err)
+		return fmt.Errorf("unable to get rows affectted")
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError(id, "no user with the given id")
+	}
+	slog.Info("user password updated", "user_id", id)
+	return nil
+}
+func (r *PostgresRepository) SetActive(id int, active bool) error {
+	result, err := r.db.Exec(`update Users set isactive=$1,updated_at=CURRENT_TIMESTAMP where id=$2`, active, id)
+	if err != nil {
+		slog.Error("unable to execute set active query", "error", err, "user_id", id)
+		return fmt.Errorf("unable to exec query %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.NewNotFoundError(id, "no user with the given id")
+	}
+	slog.Info("user active flag changed", "user_id", id, "active", active)
+	return nil
+}
+// GetPasswordHistory returns the most recent password hashes of a user, newest first.
+func (r *PostgresRepository) GetPasswordHistory(id int, limit int) ([]string, error) {
+	query := `select password from password_history where user_id=$1 order by created_at desc limit $2`
+	rows, err := r.db.Query(query, id, limit)
+	if err != nil {
+		slog.Error("failed to execute password history query", "error", err, "user_id", id)
+		return nil, err
+	}
+	defer rows.Close()
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			slog.Error("failed to scan password history row", "error", err, "user_id", id)
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// RecordPasswordChange appends the hash to the user's history and resets the password age.
+func (r *PostgresRepository) RecordPasswordChange(id int, password string) error {
+	if _, err := r.db.Exec(`insert into password_history (user_id,password) values($1,$2)`, id, password); err != nil {
+		slog.Error("unable to insert password history", "error", err, "user_id", id)
+		return fmt.Errorf("unable to exec query %w", err)
+	}
+	if _, err := r.db.Exec(`update Users set password_changed_at=CURRENT_TIMESTAMP where id=$1`, id); err != nil {
+		slog.Error("unable to update password_changed_at", "error", err, "user_id", id)
+		return fmt.Errorf("unable to exec query %w", err)
+	}
+	return nil
+}
 func (r *PostgresRepository) Delete(id int) error {
 	//can optimize by calling existsbyid here clear redundant code.
 	query := `delete from Users where id=$1`
@@ -148,7 +279,7 @@ func (r *PostgresRepository) Delete(id int) error {
 }
 
 func (r *PostgresRepository) ExistsByEmail(email string) bool { // Returns bool, not error
-	query := `SELECT EXISTS(SELECT 1 FROM Users WHERE email = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM Users WHERE lower(email) = lower($1))`
 
 	var exists bool
 	err := r.db.QueryRow(query, email).Scan(&exists)
@@ -172,7 +303,7 @@ func (r *PostgresRepository) ExistsByID(id int) bool { // Returns bool, not erro
 	return exists
 }
 func (r *PostgresRepository) ExistsByUsername(username string) bool {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE lower(username) = lower($1))`
 	var exists bool
 	err := r.db.QueryRow(query, username).Scan(&exists)
 	if err!=nil{
@@ -182,3 +313,21 @@ func (r *PostgresRepository) ExistsByUsername(username string) bool {
 	 slog.Debug("username existence check", "username", username, "exists", exists)
 	return exists
 }
+
+// duplicateError turns a unique_violation into a DuplicateError naming the
+// offending field, or returns nil for any other error. The database is the
+// source of truth: the Exists* pre-checks in the service can race.
+func duplicateError(err error, user *model.User) *errors.DuplicateError {
+	var pqErr *pq.Error
+	if !stderrors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return nil
+	}
+	switch {
+	case strings.Contains(pqErr.Constraint, "email"):
+		return errors.NewDuplicateError("email", user.Email)
+	case strings.Contains(pqErr.Constraint, "username"):
+		return errors.NewDuplicateError("username", user.Username)
+	default:
+		return errors.NewDuplicateError(pqErr.Constraint, "unique constraint violated")
+	}
+}
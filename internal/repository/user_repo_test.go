@@ -0,0 +1,92 @@
+package repository
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+)
+
+// testRepository connects to DATABASE_URL, a lib/pq connection string for
+// a database with the schema and migrations applied.
+func testRepository(t *testing.T) *PostgresRepository {
+	t.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	repo, err := NewPostgresRepository(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.conn.Close() })
+	return repo
+}
+
+// TestConcurrentCreateCaseInsensitive races two creates that differ only
+// by case in one field. The unique indexes of migrations/001 must let
+// exactly one through and duplicateError must name that field.
+func TestConcurrentCreateCaseInsensitive(t *testing.T) {
+	repo := testRepository(t)
+	suffix := fmt.Sprint(time.Now().UnixNano())
+
+	cases := []struct {
+		field string
+		a, b  model.User
+	}{
+		{
+			field: "email",
+			a:     model.User{Username: "racea" + suffix, Email: "Race" + suffix + "@example.com"},
+			b:     model.User{Username: "raceb" + suffix, Email: "race" + suffix + "@EXAMPLE.com"},
+		},
+		{
+			field: "username",
+			a:     model.User{Username: "Racer" + suffix, Email: "racer-a" + suffix + "@example.com"},
+			b:     model.User{Username: "rACER" + suffix, Email: "racer-b" + suffix + "@example.com"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.field, func(t *testing.T) {
+			users := []*model.User{&tc.a, &tc.b}
+			errs := make([]error, len(users))
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for i, u := range users {
+				u.Password = "not-a-real-hash"
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					errs[i] = repo.Create(u)
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			var created, duplicates int
+			for i, err := range errs {
+				var dup *errors.DuplicateError
+				switch {
+				case err == nil:
+					created++
+					id := users[i].ID
+					t.Cleanup(func() { repo.Delete(id) })
+				case stderrors.As(err, &dup):
+					duplicates++
+					if dup.Resource != tc.field {
+						t.Errorf("duplicate reported on %v, want %s", dup.Resource, tc.field)
+					}
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			if created != 1 || duplicates != 1 {
+				t.Errorf("got %d created and %d duplicates, want 1 and 1", created, duplicates)
+			}
+		})
+	}
+}
\ No newline at end of file
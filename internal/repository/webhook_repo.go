@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+	"user-management/internal/errors"
+	"user-management/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepo interface {
+	CreateWebhook(w *model.Webhook) error
+	GetWebhook(id int) (*model.Webhook, error)
+	ListWebhooks() ([]model.Webhook, error)
+	UpdateWebhook(w model.Webhook) error
+	DeleteWebhook(id int) error
+	// ListSubscribers returns the active webhooks that want eventType.
+	ListSubscribers(eventType string) ([]model.Webhook, error)
+	// RecordWebhookFailure bumps the failure streak and disables the webhook
+	// once it reaches disableAfter. It reports whether the webhook is now disabled.
+	RecordWebhookFailure(id int, disableAfter int) (bool, error)
+	ResetWebhookFailures(id int) error
+
+	// CreateDelivery records a delivery unless the webhook already has one
+	// for the event, in which case d is left without an id.
+	CreateDelivery(d *model.WebhookDelivery) error
+	GetDelivery(id int64) (*model.WebhookDelivery, error)
+	ListDeliveries(webhookID int, limit int) ([]model.WebhookDelivery, error)
+	// DueDeliveries returns pending deliveries whose next attempt is due.
+	DueDeliveries(limit int) ([]model.WebhookDelivery, error)
+	UpdateDelivery(d model.WebhookDelivery) error
+	RecordAttempt(a model.WebhookAttempt) error
+	ListAttempts(deliveryID int64) ([]model.WebhookAttempt, error)
+}
+
+const webhookColumns = `id,url,event_types,secret,active,consecutive_failures,created_at,updated_at`
+
+func scanWebhook(row interface{ Scan(...any) error }) (*model.Webhook, error) {
+	var w model.Webhook
+	err := row.Scan(&w.ID, &w.URL, pq.Array(&w.EventTypes), &w.Secret, &w.Active, &w.ConsecutiveFailures, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *PostgresRepository) CreateWebhook(w *model.Webhook) error {
+	query := `insert into webhooks (url,event_types,secret) values($1,$2,$3) returning id,active,created_at,updated_at`
+	err := r.db.QueryRow(query, w.URL, pq.Array(w.EventTypes), w.Secret).Scan(&w.ID, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		slog.Error("failed to create webhook", "error", err, "url", w.URL)
+		return err
+	}
+	slog.Info("webhook created", "webhook_id", w.ID, "url", w.URL)
+	return nil
+}
+
+func (r *PostgresRepository) GetWebhook(id int) (*model.Webhook, error) {
+	w, err := scanWebhook(r.db.QueryRow(`select `+webhookColumns+` from webhooks where id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError(id, "no webhook with that id")
+	}
+	if err != nil {
+		slog.Error("error while scanning webhook", "error", err, "webhook_id", id)
+		return nil, err
+	}
+	return w, nil
+}
+
+func (r *PostgresRepository) ListWebhooks() ([]model.Webhook, error) {
+	return r.queryWebhooks(`select ` + webhookColumns + ` from webhooks order by id`)
+}
+
+func (r *PostgresRepository) ListSubscribers(eventType string) ([]model.Webhook, error) {
+	return r.queryWebhooks(`select `+webhookColumns+` from webhooks
+		where active and (cardinality(event_types)=0 or $1=any(event_types)) order by id`, eventType)
+}
+
+func (r *PostgresRepository) queryWebhooks(query string, args ...any) ([]model.Webhook, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("failed to query webhooks", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var hooks []model.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			slog.Error("failed to scan webhook row", "error", err)
+			return nil, err
+		}
+		hooks = append(hooks, *w)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *PostgresRepository) UpdateWebhook(w model.Webhook) error {
+	query := `update webhooks set url=$1,event_types=$2,active=$3,consecutive_failures=$4,updated_at=CURRENT_TIMESTAMP where id=$5`
+	result, err := r.db.Exec(query, w.URL, pq.Array(w.EventTypes), w.Active, w.ConsecutiveFailures, w.ID)
+	if err != nil {
+		slog.Error("unable to execute webhook update query", "error", err, "webhook_id", w.ID)
+		return fmt.Errorf("unable to exec query %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.NewNotFoundError(w.ID, "no webhook with the given id")
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteWebhook(id int) error {
+	result, err := r.db.Exec(`delete from webhooks where id=$1`, id)
+	if err != nil {
+		slog.Error("unable to execute webhook delete query", "error", err, "webhook_id", id)
+		return fmt.Errorf("unable to exec query %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.NewNotFoundError(id, "no webhook with the given id")
+	}
+	slog.Info("webhook deleted", "webhook_id", id)
+	return nil
+}
+
+func (r *PostgresRepository) RecordWebhookFailure(id int, disableAfter int) (bool, error) {
+	query := `update webhooks set consecutive_failures=consecutive_failures+1,
+		active=active and consecutive_failures+1<$2, updated_at=CURRENT_TIMESTAMP
+		where id=$1 returning active`
+	var active bool
+	if err := r.db.QueryRow(query, id, disableAfter).Scan(&active); err != nil {
+		slog.Error("unable to record webhook failure", "error", err, "webhook_id", id)
+		return false, err
+	}
+	return !active, nil
+}
+
+func (r *PostgresRepository) ResetWebhookFailures(id int) error {
+	_, err := r.db.Exec(`update webhooks set consecutive_failures=0 where id=$1 and consecutive_failures<>0`, id)
+	return err
+}
+
+const deliveryColumns = `id,webhook_id,event_id,event_type,payload,status,attempts,next_attempt_at,coalesce(last_status_code,0),coalesce(last_error,''),created_at`
+
+func scanDelivery(row interface{ Scan(...any) error }) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *PostgresRepository) CreateDelivery(d *model.WebhookDelivery) error {
+	query := `insert into webhook_deliveries (webhook_id,event_id,event_type,payload,status,next_attempt_at)
+		values($1,$2,$3,$4,$5,$6) on conflict (webhook_id,event_id) do nothing returning id,created_at`
+	err := r.db.QueryRow(query, d.WebhookID, d.EventID, d.EventType, d.Payload, d.Status, d.NextAttemptAt).Scan(&d.ID, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		// recorded by an earlier attempt at dispatching the event
+		return nil
+	}
+	if err != nil {
+		slog.Error("failed to create webhook delivery", "error", err, "webhook_id", d.WebhookID, "event_id", d.EventID)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetDelivery(id int64) (*model.WebhookDelivery, error) {
+	d, err := scanDelivery(r.db.QueryRow(`select `+deliveryColumns+` from webhook_deliveries where id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError(id, "no webhook delivery with that id")
+	}
+	if err != nil {
+		slog.Error("error while scanning webhook delivery", "error", err, "delivery_id", id)
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *PostgresRepository) ListDeliveries(webhookID int, limit int) ([]model.WebhookDelivery, error) {
+	return r.queryDeliveries(`select `+deliveryColumns+` from webhook_deliveries
+		where webhook_id=$1 order by id desc limit $2`, webhookID, limit)
+}
+
+func (r *PostgresRepository) DueDeliveries(limit int) ([]model.WebhookDelivery, error) {
+	return r.queryDeliveries(`select `+deliveryColumns+` from webhook_deliveries
+		where status=$1 and next_attempt_at<=$2 order by id limit $3`, model.DeliveryPending, time.Now(), limit)
+}
+
+func (r *PostgresRepository) queryDeliveries(query string, args ...any) ([]model.WebhookDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("failed to query webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			slog.Error("failed to scan webhook delivery row", "error", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *PostgresRepository) UpdateDelivery(d model.WebhookDelivery) error {
+	query := `update webhook_deliveries set status=$1,attempts=$2,next_attempt_at=$3,last_status_code=$4,last_error=$5,
+		updated_at=CURRENT_TIMESTAMP where id=$6`
+	_, err := r.db.Exec(query, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.ID)
+	if err != nil {
+		slog.Error("unable to update webhook delivery", "error", err, "delivery_id", d.ID)
+	}
+	return err
+}
+
+func (r *PostgresRepository) RecordAttempt(a model.WebhookAttempt) error {
+	query := `insert into webhook_delivery_attempts (delivery_id,attempt,status_code,error,duration_ms) values($1,$2,$3,$4,$5)`
+	_, err := r.db.Exec(query, a.DeliveryID, a.Attempt, a.StatusCode, a.Error, a.DurationMs)
+	if err != nil {
+		slog.Error("unable to record webhook attempt", "error", err, "delivery_id", a.DeliveryID)
+	}
+	return err
+}
+
+func (r *PostgresRepository) ListAttempts(deliveryID int64) ([]model.WebhookAttempt, error) {
+	query := `select delivery_id,attempt,coalesce(status_code,0),coalesce(error,''),duration_ms,created_at
+		from webhook_delivery_attempts where delivery_id=$1 order by id`
+	rows, err := r.db.Query(query, deliveryID)
+	if err != nil {
+		slog.Error("failed to query webhook attempts", "error", err, "delivery_id", deliveryID)
+		return nil, err
+	}
+	defer rows.Close()
+	var attempts []model.WebhookAttempt
+	for rows.Next() {
+		var a model.WebhookAttempt
+		if err := rows.Scan(&a.DeliveryID, &a.Attempt, &a.StatusCode, &a.Error, &a.DurationMs, &a.CreatedAt); err != nil {
+			slog.Error("failed to scan webhook attempt row", "error", err)
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
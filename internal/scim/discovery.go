@@ -0,0 +1,138 @@
+package scim
+
+// The discovery documents of RFC 7644 section 4. They describe what this
+// service supports and never change at runtime.
+
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  Meta                   `json:"meta"`
+}
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type ResourceType struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Endpoint string   `json:"endpoint"`
+	Schema   string   `json:"schema"`
+	Meta     Meta     `json:"meta"`
+}
+
+type SchemaDefinition struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        Meta        `json:"meta"`
+}
+
+type Attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []Attribute `json:"subAttributes,omitempty"`
+}
+
+// MaxResults caps the count parameter of list requests.
+const MaxResults = 1000
+
+func NewServiceProviderConfig() ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          Supported{Supported: true},
+		Bulk:           BulkSupport{},
+		Filter:         FilterSupport{Supported: true, MaxResults: MaxResults},
+		ChangePassword: Supported{},
+		Sort:           Supported{},
+		ETag:           Supported{},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer token",
+			Description: "The token configured for the provisioning client",
+			Primary:     true,
+		}},
+		Meta: Meta{ResourceType: "ServiceProviderConfig", Location: BasePath + "/ServiceProviderConfig"},
+	}
+}
+
+func NewResourceTypes() []ResourceType {
+	return []ResourceType{{
+		Schemas:  []string{SchemaResourceType},
+		ID:       "User",
+		Name:     "User",
+		Endpoint: "/Users",
+		Schema:   SchemaUser,
+		Meta:     Meta{ResourceType: "ResourceType", Location: BasePath + "/ResourceTypes/User"},
+	}}
+}
+
+func NewSchemas() []SchemaDefinition {
+	text := func(name string, required bool, uniqueness string) Attribute {
+		return Attribute{Name: name, Type: "string", Required: required, Mutability: "readWrite", Returned: "default", Uniqueness: uniqueness}
+	}
+	password := text("password", false, "none")
+	password.Mutability, password.Returned = "writeOnly", "never"
+	id := text("id", false, "server")
+	id.Mutability, id.Returned, id.CaseExact = "readOnly", "always", true
+	primary := Attribute{Name: "primary", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+	return []SchemaDefinition{{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaUser,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []Attribute{
+			id,
+			text("userName", true, "server"),
+			{
+				Name: "name", Type: "complex", Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+				SubAttributes: []Attribute{
+					text("formatted", false, "none"),
+					text("givenName", false, "none"),
+					text("familyName", false, "none"),
+				},
+			},
+			text("displayName", false, "none"),
+			{
+				Name: "emails", Type: "complex", MultiValued: true, Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+				SubAttributes: []Attribute{text("value", true, "server"), text("type", false, "none"), primary},
+			},
+			{Name: "active", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+			password,
+		},
+		Meta: Meta{ResourceType: "Schema", Location: BasePath + "/Schemas/" + SchemaUser},
+	}}
+}
\ No newline at end of file
@@ -0,0 +1,304 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed SCIM filter expression.
+type Filter interface {
+	Matches(u User) bool
+}
+
+// ParseFilter parses the filter query parameter. It supports the comparison
+// operators eq, ne, co, sw, ew, gt, ge, lt, le, the presence operator pr,
+// and, or, not and parentheses. Attribute names and string comparisons are
+// case-insensitive, as the core User attributes are caseExact=false.
+func ParseFilter(s string) (Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, token{text: string(c)})
+			i++
+		case c == '"':
+			var b strings.Builder
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{text: b.String(), quoted: true})
+		default:
+			start := i
+			for i < len(s) && s[i] != ' ' && s[i] != '(' && s[i] != ')' && s[i] != '"' {
+				i++
+			}
+			tokens = append(tokens, token{text: s[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peekKeyword(word string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, word)
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("unexpected end of filter")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) or() (Filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Filter, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (Filter, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		f, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{f}, nil
+	}
+	if p.peekKeyword("(") {
+		p.pos++
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekKeyword(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return f, nil
+	}
+	attr, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if attr.quoted || !knownAttribute(attr.text) {
+		return nil, fmt.Errorf("unsupported attribute %q", attr.text)
+	}
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	cmp := compareFilter{attr: attributeName(attr.text), op: strings.ToLower(op.text)}
+	if cmp.op == "pr" {
+		return cmp, nil
+	}
+	switch cmp.op {
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, fmt.Errorf("unsupported operator %q", op.text)
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	cmp.value = value.text
+	if !value.quoted {
+		// true, false, null and numbers are unquoted
+		if _, err := strconv.ParseFloat(value.text, 64); err != nil && value.text != "true" && value.text != "false" && value.text != "null" {
+			return nil, fmt.Errorf("invalid value %q", value.text)
+		}
+	}
+	return cmp, nil
+}
+
+type andFilter struct{ left, right Filter }
+type orFilter struct{ left, right Filter }
+type notFilter struct{ inner Filter }
+
+func (f andFilter) Matches(u User) bool { return f.left.Matches(u) && f.right.Matches(u) }
+func (f orFilter) Matches(u User) bool  { return f.left.Matches(u) || f.right.Matches(u) }
+func (f notFilter) Matches(u User) bool { return !f.inner.Matches(u) }
+
+type compareFilter struct {
+	attr  string
+	op    string
+	value string
+}
+
+func (f compareFilter) Matches(u User) bool {
+	values := attributeValues(u, f.attr)
+	if f.op == "pr" {
+		return len(values) > 0
+	}
+	if f.op == "ne" {
+		for _, v := range values {
+			if strings.EqualFold(v, f.value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if compare(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(actual, op, expected string) bool {
+	a, e := strings.ToLower(actual), strings.ToLower(expected)
+	switch op {
+	case "eq":
+		return a == e
+	case "co":
+		return strings.Contains(a, e)
+	case "sw":
+		return strings.HasPrefix(a, e)
+	case "ew":
+		return strings.HasSuffix(a, e)
+	case "gt":
+		return a > e
+	case "ge":
+		return a >= e
+	case "lt":
+		return a < e
+	case "le":
+		return a <= e
+	}
+	return false
+}
+
+var attributes = []string{
+	"id", "username", "displayname", "active",
+	"name.formatted", "name.givenname", "name.familyname",
+	"emails", "emails.value", "emails.type", "emails.primary",
+	// sub-attributes, for value filters such as emails[type eq "work"]
+	"value", "type", "primary",
+}
+
+// attributeName lowercases name and drops the optional schema URN prefix.
+func attributeName(name string) string {
+	return strings.TrimPrefix(strings.ToLower(name), strings.ToLower(SchemaUser)+":")
+}
+
+func knownAttribute(name string) bool {
+	name = attributeName(name)
+	for _, a := range attributes {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeValues returns the values of attr, as returned by attributeName,
+// on u. Empty values count as absent.
+func attributeValues(u User, attr string) []string {
+	var values []string
+	add := func(v string) {
+		if strings.TrimFunc(v, unicode.IsSpace) != "" {
+			values = append(values, v)
+		}
+	}
+	switch attr {
+	case "id":
+		add(u.ID)
+	case "username":
+		add(u.UserName)
+	case "displayname":
+		add(u.DisplayName)
+	case "active":
+		if u.Active != nil {
+			add(strconv.FormatBool(*u.Active))
+		}
+	case "name.formatted", "name.givenname", "name.familyname":
+		if u.Name != nil {
+			add(map[string]string{
+				"name.formatted":  u.Name.Formatted,
+				"name.givenname":  u.Name.GivenName,
+				"name.familyname": u.Name.FamilyName,
+			}[attr])
+		}
+	case "emails", "emails.value", "value":
+		for _, e := range u.Emails {
+			add(e.Value)
+		}
+	case "emails.type", "type":
+		for _, e := range u.Emails {
+			add(e.Type)
+		}
+	case "emails.primary", "primary":
+		for _, e := range u.Emails {
+			add(strconv.FormatBool(e.Primary))
+		}
+	}
+	return values
+}
\ No newline at end of file
@@ -0,0 +1,190 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply runs the operations against u in order. Paths may be userName,
+// displayName, active, name, name.formatted, name.givenName,
+// name.familyName, emails, emails.value and emails[<filter>].value. Without
+// a path the value is an object of attributes to set. externalId is accepted
+// and ignored since it is not stored.
+func Apply(u *User, ops []PatchOperation) error {
+	for _, op := range ops {
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if op.Path == "" {
+			if kind == "remove" {
+				return fmt.Errorf("remove requires a path")
+			}
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return fmt.Errorf("value must be an object of attributes when path is omitted")
+			}
+			// displayName first, so name wins when both are set
+			paths := make([]string, 0, len(attrs))
+			for path := range attrs {
+				paths = append(paths, path)
+			}
+			sort.SliceStable(paths, func(i, j int) bool {
+				return attributeName(paths[i]) == "displayname" && attributeName(paths[j]) != "displayname"
+			})
+			for _, path := range paths {
+				if err := set(u, path, attrs[path], kind); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := set(u, op.Path, op.Value, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func set(u *User, path string, value json.RawMessage, kind string) error {
+	remove := kind == "remove"
+	decode := func(dst any) error {
+		if err := json.Unmarshal(value, dst); err != nil {
+			return fmt.Errorf("invalid value for %s", path)
+		}
+		return nil
+	}
+	ensureName := func() {
+		if u.Name == nil {
+			u.Name = &Name{}
+		}
+	}
+	name := attributeName(path)
+	if strings.HasPrefix(name, "emails[") {
+		return setEmailValue(u, path, value, remove)
+	}
+	switch name {
+	case "username":
+		if remove {
+			return fmt.Errorf("userName is required")
+		}
+		return decode(&u.UserName)
+	// displayName and name render the same stored name, so a change to
+	// either replaces both
+	case "displayname":
+		u.DisplayName, u.Name = "", nil
+		if remove {
+			return nil
+		}
+		return decode(&u.DisplayName)
+	case "active":
+		if remove {
+			return fmt.Errorf("active cannot be removed")
+		}
+		var active bool
+		// some providers send "False" as a string
+		if err := json.Unmarshal(value, &active); err != nil {
+			var s string
+			if decode(&s) != nil || (!strings.EqualFold(s, "true") && !strings.EqualFold(s, "false")) {
+				return fmt.Errorf("invalid value for active")
+			}
+			active = strings.EqualFold(s, "true")
+		}
+		u.Active = &active
+	case "name":
+		u.Name, u.DisplayName = nil, ""
+		if remove {
+			return nil
+		}
+		return decode(&u.Name)
+	case "name.formatted", "name.givenname", "name.familyname":
+		ensureName()
+		field := map[string]*string{
+			"name.formatted":  &u.Name.Formatted,
+			"name.givenname":  &u.Name.GivenName,
+			"name.familyname": &u.Name.FamilyName,
+		}[name]
+		*field = ""
+		u.DisplayName = ""
+		if name == "name.formatted" {
+			// a new formatted name is split again
+			u.Name.GivenName, u.Name.FamilyName = "", ""
+		} else {
+			// formatted is joined again from the parts
+			u.Name.Formatted = ""
+		}
+		if remove {
+			return nil
+		}
+		return decode(field)
+	case "emails":
+		if remove {
+			return fmt.Errorf("an email is required")
+		}
+		var emails []Email
+		if err := decode(&emails); err != nil {
+			return err
+		}
+		if kind == "add" {
+			u.Emails = append(emails, u.Emails...)
+		} else {
+			u.Emails = emails
+		}
+	case "emails.value":
+		if remove {
+			return fmt.Errorf("an email is required")
+		}
+		var email string
+		if err := decode(&email); err != nil {
+			return err
+		}
+		u.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+	case "externalid":
+		// not stored; accepted since clients send it with every change
+	default:
+		return fmt.Errorf("unsupported path %q", path)
+	}
+	return nil
+}
+
+// setEmailValue handles paths such as emails[type eq "work"].value. Since
+// only one email is stored, the filter only has to match it.
+func setEmailValue(u *User, path string, value json.RawMessage, remove bool) error {
+	start, end := strings.Index(path, "["), strings.LastIndex(path, "]")
+	if end < start || !strings.EqualFold(path[end+1:], ".value") {
+		return fmt.Errorf("unsupported path %q", path)
+	}
+	filter, err := ParseFilter(path[start+1 : end])
+	if err != nil {
+		return fmt.Errorf("invalid path filter: %v", err)
+	}
+	if remove {
+		return fmt.Errorf("an email is required")
+	}
+	var email string
+	if err := json.Unmarshal(value, &email); err != nil {
+		return fmt.Errorf("invalid value for %s", path)
+	}
+	for i, e := range u.Emails {
+		// evaluate the filter against each email as if it were the only one
+		if filter.Matches(User{Emails: []Email{e}}) {
+			u.Emails[i].Value = email
+			return nil
+		}
+	}
+	u.Emails = append([]Email{{Value: email, Type: "work", Primary: len(u.Emails) == 0}}, u.Emails...)
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,138 @@
+// Package scim maps users onto the SCIM 2.0 core User resource (RFC 7643)
+// and implements the filter and PATCH parts of the protocol (RFC 7644).
+package scim
+
+import (
+	"strconv"
+	"user-management/internal/model"
+)
+
+const (
+	ContentType = "application/scim+json"
+
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+
+	// BasePath is where the SCIM API is mounted.
+	BasePath = "/scim/v2"
+)
+
+// User is the subset of the core User schema the service stores. name maps
+// onto the given, family and formatted names of the user. externalId is not
+// stored, so it is not part of the resource.
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Password    string   `json:"password,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+type ListResponse[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+// NewListResponse wraps one page of resources, the first of which is at
+// startIndex (1-based), out of total.
+func NewListResponse[T any](page []T, startIndex, total int) ListResponse[T] {
+	return ListResponse[T]{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+// Error is the SCIM error body. Status is a string by specification.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewError(status int, scimType, detail string) Error {
+	return Error{Schemas: []string{SchemaError}, Status: strconv.Itoa(status), ScimType: scimType, Detail: detail}
+}
+
+// FromUser renders u as a SCIM resource. Secret fields are never included.
+func FromUser(u *model.User) User {
+	active := u.IsActive
+	res := User{
+		Schemas:  []string{SchemaUser},
+		ID:       strconv.Itoa(u.ID),
+		UserName: u.Username,
+		Emails:   []Email{{Value: u.Email, Type: "work", Primary: true}},
+		Active:   &active,
+		Meta:     &Meta{ResourceType: "User", Location: BasePath + "/Users/" + strconv.Itoa(u.ID)},
+	}
+	if u.Name != "" || u.GivenName != "" || u.FamilyName != "" {
+		res.Name = &Name{Formatted: u.Name, GivenName: u.GivenName, FamilyName: u.FamilyName}
+		res.DisplayName = u.Name
+	}
+	return res
+}
+
+// ToUser returns the stored fields of the resource. givenName and familyName
+// are stored as they are and formatted is joined from them; a resource with
+// only name.formatted, or only displayName, has it split like a v1 name.
+// name.formatted wins over displayName when both are sent.
+func (r User) ToUser() model.User {
+	user := model.User{
+		Username: r.UserName,
+		Email:    r.PrimaryEmail(),
+		Password: r.Password,
+		Name:     r.DisplayName,
+	}
+	if r.Name != nil {
+		if r.Name.Formatted != "" {
+			user.Name = r.Name.Formatted
+		}
+		user.GivenName, user.FamilyName = r.Name.GivenName, r.Name.FamilyName
+	}
+	return user
+}
+
+// PrimaryEmail returns the email marked primary, or the first one.
+func (r User) PrimaryEmail() string {
+	for _, e := range r.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(r.Emails) > 0 {
+		return r.Emails[0].Value
+	}
+	return ""
+}
\ No newline at end of file
@@ -0,0 +1,72 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+	"user-management/internal/model"
+)
+
+func TestNameMapsOntoGivenAndFamilyName(t *testing.T) {
+	u := &model.User{ID: 7, Username: "ada", Email: "ada@example.com", Name: "Ada King Lovelace", GivenName: "Ada", FamilyName: "King Lovelace"}
+	res := FromUser(u)
+	if res.Name == nil || res.Name.GivenName != "Ada" || res.Name.FamilyName != "King Lovelace" || res.Name.Formatted != "Ada King Lovelace" {
+		t.Fatalf("FromUser name = %+v, want the stored parts", res.Name)
+	}
+
+	got := res.ToUser()
+	if got.GivenName != "Ada" || got.FamilyName != "King Lovelace" {
+		t.Errorf("ToUser = %+v, want the parts unchanged", got)
+	}
+
+	// a new formatted name replaces the parts, which are split from it again
+	err := Apply(&res, []PatchOperation{{Op: "replace", Path: "name.formatted", Value: json.RawMessage(`"Augusta Ada King"`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.ToUser(); got.Name != "Augusta Ada King" || got.GivenName != "" || got.FamilyName != "" {
+		t.Errorf("after replacing name.formatted ToUser = %+v, want only the formatted name", got)
+	}
+
+	// a new family name keeps the given name
+	res = FromUser(u)
+	err = Apply(&res, []PatchOperation{{Op: "replace", Path: "name.familyName", Value: json.RawMessage(`"Lovelace"`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.ToUser(); got.GivenName != "Ada" || got.FamilyName != "Lovelace" {
+		t.Errorf("after replacing name.familyName ToUser = %+v, want Ada Lovelace", got)
+	}
+}
+
+func TestPatchDisplayNameAndRemoveName(t *testing.T) {
+	u := &model.User{ID: 7, Username: "ada", Email: "ada@example.com", Name: "Ada Lovelace", GivenName: "Ada", FamilyName: "Lovelace"}
+
+	res := FromUser(u)
+	err := Apply(&res, []PatchOperation{{Op: "replace", Path: "displayName", Value: json.RawMessage(`"Augusta King"`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.ToUser(); got.Name != "Augusta King" || got.GivenName != "" || got.FamilyName != "" {
+		t.Errorf("after replacing displayName ToUser = %+v, want the new name to be split again", got)
+	}
+
+	for _, path := range []string{"name", "displayName"} {
+		res = FromUser(u)
+		if err := Apply(&res, []PatchOperation{{Op: "remove", Path: path}}); err != nil {
+			t.Fatal(err)
+		}
+		if got := res.ToUser(); got.Name != "" || got.GivenName != "" || got.FamilyName != "" {
+			t.Errorf("after removing %s ToUser = %+v, want no name", path, got)
+		}
+	}
+
+	// without a path the attributes are set together; name wins
+	res = FromUser(u)
+	err = Apply(&res, []PatchOperation{{Op: "replace", Value: json.RawMessage(`{"name":{"formatted":"Ada King"},"displayName":"Augusta King"}`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.ToUser(); got.Name != "Ada King" {
+		t.Errorf("after replacing name ToUser = %+v, want Ada King", got)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,132 @@
+// Package server assembles the HTTP routes of the service.
+package server
+
+import (
+	"net/http"
+	"user-management/internal/config"
+	"user-management/internal/graph"
+	"user-management/internal/handlers"
+	"user-management/internal/imports"
+	"user-management/internal/middleware"
+	"user-management/internal/openapi"
+	"user-management/internal/scim"
+	"user-management/internal/service"
+	"user-management/internal/stream"
+
+	"github.com/gorilla/mux"
+)
+
+// Deps are the services the routes are served by. Background work such as
+// the outbox dispatcher is started by the caller, not here.
+type Deps struct {
+	Users       *service.UserService
+	Webhooks    *service.WebhookService
+	Idempotency *middleware.Idempotency
+	Broker      *stream.Broker
+}
+
+// NewRouter registers every route of spec that cfg enables. Requests are
+// checked against spec before they reach the handlers. Wrap the result in
+// middleware.NegotiateVersion to serve the unversioned paths.
+func NewRouter(cfg *config.Config, spec *openapi.Document, deps Deps) (*mux.Router, error) {
+	userService, idempotency := deps.Users, deps.Idempotency
+	handler := handlers.NewUserHandler(userService)
+	importHandler := handlers.NewImportHandler(userService, imports.NewJobs(cfg.Import.JobRetention), cfg.Import)
+	webhookHandler := handlers.NewWebhookHandler(deps.Webhooks, cfg.WebhooksAdminToken)
+	streamHandler := handlers.NewEventStreamHandler(deps.Broker, cfg.Stream.Heartbeat)
+	authenticator := middleware.NewAuthenticator(cfg, userService)
+	validator := middleware.NewRequestValidator(spec, cfg.ValidateResponses)
+
+	router := mux.NewRouter()
+	//the REST routes are mounted under /v1 and /v2; unversioned paths are
+	//sent to one of them by the Accept header, see middleware.NegotiateVersion
+	deprecation := middleware.NewDeprecation(cfg.V1, "/v1", "/v2")
+	mountAPI := func(prefix string, handler *handlers.UserHandler, versionMiddleware ...mux.MiddlewareFunc) {
+		subrouter := func(mw ...mux.MiddlewareFunc) *mux.Router {
+			sub := router.PathPrefix(prefix).Subrouter()
+			sub.Use(versionMiddleware...)
+			sub.Use(mw...)
+			return sub
+		}
+		public := subrouter(validator.Middleware)
+		public.Handle("/users", idempotency.Middleware(http.HandlerFunc(handler.CreateHandler))).Methods("POST")
+		public.HandleFunc("/auth/login", handler.LoginHandler).Methods("POST")
+		//protected routes authenticationrequired
+		protected := subrouter(authenticator.JWTMiddleware, validator.Middleware, idempotency.Middleware)
+
+		protected.HandleFunc("/users", handler.GetAllHandler).Methods("GET")
+		protected.HandleFunc("/users/export", handler.ExportHandler).Methods("GET")
+		protected.HandleFunc("/users:batch", handler.BatchHandler).Methods("POST")
+		protected.HandleFunc("/users/import/jobs/{id}", importHandler.GetJobHandler).Methods("GET")
+		protected.HandleFunc("/users/import/jobs/{id}/report", importHandler.ReportHandler).Methods("GET")
+		protected.HandleFunc("/users/events", streamHandler.StreamHandler).Methods("GET")
+		protected.HandleFunc("/users/me", handler.GetMeHandler).Methods("GET")
+		protected.HandleFunc("/users/me", handler.PatchMeHandler).Methods("PATCH")
+		protected.HandleFunc("/users/me", handler.DeleteMeHandler).Methods("DELETE")
+		protected.HandleFunc("/auth/session", handler.SessionHandler).Methods("GET")
+		protected.HandleFunc("/users/{id:[0-9]+}", handler.GetByIDHandler).Methods("GET")
+		protected.HandleFunc("/users/{id:[0-9]+}", handler.UpdateHandler).Methods("PUT")
+		protected.HandleFunc("/users/{id:[0-9]+}", handler.DeleteHandler).Methods("DELETE")
+
+		//webhook subscriptions see every user's events, so only operators manage them;
+		//no idempotency keys here, a new webhook's response carries its signing secret
+		if cfg.WebhooksAdminToken != "" {
+			admin := subrouter(webhookHandler.Authenticate, validator.Middleware)
+			admin.HandleFunc("/webhooks", webhookHandler.ListHandler).Methods("GET")
+			admin.HandleFunc("/webhooks", webhookHandler.CreateHandler).Methods("POST")
+			admin.HandleFunc("/webhooks/{id:[0-9]+}", webhookHandler.GetHandler).Methods("GET")
+			admin.HandleFunc("/webhooks/{id:[0-9]+}", webhookHandler.UpdateHandler).Methods("PUT")
+			admin.HandleFunc("/webhooks/{id:[0-9]+}", webhookHandler.DeleteHandler).Methods("DELETE")
+			admin.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", webhookHandler.ListDeliveriesHandler).Methods("GET")
+			admin.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{deliveryID:[0-9]+}", webhookHandler.GetDeliveryHandler).Methods("GET")
+			admin.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{deliveryID:[0-9]+}/redeliver", webhookHandler.RedeliverHandler).Methods("POST")
+		}
+
+		//imports are streamed and can be far larger than the idempotency
+		//middleware buffers, so they take no idempotency keys
+		uploads := subrouter(authenticator.JWTMiddleware, validator.Middleware)
+		uploads.HandleFunc("/users/import", importHandler.ImportHandler).Methods("POST")
+
+		//routes that also accept the short-lived token issued for an expired password;
+		//no idempotency keys here, the response carries a new token that must not be stored
+		passwordChange := subrouter(authenticator.PasswordChangeJWTMiddleware, validator.Middleware)
+		passwordChange.HandleFunc("/users/me/password", handler.ChangePasswordHandler).Methods("POST")
+	}
+	mountAPI("/v1", handler, deprecation.Middleware)
+	mountAPI("/v2", handler.V2())
+
+	//GraphQL checks the token itself since createUser needs none
+	router.Handle("/graphql", authenticator.OptionalJWTMiddleware(graph.NewHandler(userService))).Methods("POST")
+
+	//token introspection for other services, authenticated with client credentials
+	if len(cfg.IntrospectionClients) > 0 {
+		introspectionHandler := handlers.NewIntrospectionHandler(userService, cfg.IntrospectionClients)
+		router.HandleFunc("/oauth2/introspect", introspectionHandler.IntrospectHandler).Methods("POST")
+	}
+
+	//SCIM provisioning, authenticated with its own bearer token
+	if cfg.SCIMToken != "" {
+		scimHandler := handlers.NewSCIMHandler(userService, cfg.SCIMToken)
+		scimRouter := router.PathPrefix(scim.BasePath).Subrouter()
+		scimRouter.Use(scimHandler.Authenticate)
+		scimRouter.HandleFunc("/Users", scimHandler.ListHandler).Methods("GET")
+		scimRouter.HandleFunc("/Users", scimHandler.CreateHandler).Methods("POST")
+		scimRouter.HandleFunc("/Users/{id}", scimHandler.GetHandler).Methods("GET")
+		scimRouter.HandleFunc("/Users/{id}", scimHandler.ReplaceHandler).Methods("PUT")
+		scimRouter.HandleFunc("/Users/{id}", scimHandler.PatchHandler).Methods("PATCH")
+		scimRouter.HandleFunc("/Users/{id}", scimHandler.DeleteHandler).Methods("DELETE")
+		scimRouter.HandleFunc("/ServiceProviderConfig", scimHandler.ServiceProviderConfigHandler).Methods("GET")
+		scimRouter.HandleFunc("/ResourceTypes", scimHandler.ResourceTypesHandler).Methods("GET")
+		scimRouter.HandleFunc("/Schemas", scimHandler.SchemasHandler).Methods("GET")
+	}
+
+	//API docs
+	docsHandler, err := handlers.NewDocsHandler(spec)
+	if err != nil {
+		return nil, err
+	}
+	router.HandleFunc("/openapi.json", docsHandler.SpecHandler).Methods("GET")
+	router.HandleFunc("/docs", docsHandler.UIHandler).Methods("GET")
+	router.HandleFunc("/docs/{asset}", docsHandler.AssetHandler).Methods("GET")
+	return router, nil
+}
\ No newline at end of file
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"user-management/internal/auth"
+	"user-management/internal/config"
+	"user-management/internal/middleware"
+	"user-management/internal/model"
+	"user-management/internal/openapi"
+	"user-management/internal/repository"
+	"user-management/internal/service"
+	"user-management/internal/stream"
+)
+
+// keyStore records the idempotency records the middleware writes.
+type keyStore struct {
+	mu        sync.Mutex
+	claimed   []model.IdempotencyRecord
+	completed []model.IdempotencyRecord
+}
+
+func (s *keyStore) ClaimIdempotencyKey(rec model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed = append(s.claimed, rec)
+	return nil, true, nil
+}
+
+func (s *keyStore) CompleteIdempotencyKey(rec model.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed = append(s.completed, rec)
+	return nil
+}
+
+func (s *keyStore) ReleaseIdempotencyKey(scope, key string) error { return nil }
+
+func (s *keyStore) PurgeExpiredIdempotencyKeys() (int64, error) { return 0, nil }
+
+// webhookStore keeps created webhooks; nothing else is called by the test.
+type webhookStore struct {
+	repository.WebhookRepo
+	created []*model.Webhook
+}
+
+func (s *webhookStore) CreateWebhook(w *model.Webhook) error {
+	w.ID = len(s.created) + 1
+	w.Active = true
+	s.created = append(s.created, w)
+	return nil
+}
+
+func newTestRouter(t *testing.T, keys *keyStore, hooks *webhookStore) http.Handler {
+	t.Helper()
+	cfg := &config.Config{
+		JWTSecret:          "test",
+		JWTExpiry:          time.Hour,
+		WebhooksAdminToken: "admin-token",
+		Stream:             config.StreamConfig{ReplaySize: 10, SubscriberBuffer: 1, Heartbeat: time.Second},
+		Webhooks:           config.WebhookConfig{AllowPrivateNetworks: true},
+		Import:             config.ImportConfig{AsyncThreshold: 1 << 20, MaxSize: 100 << 20, JobRetention: time.Hour},
+	}
+	policy, err := auth.NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher, err := auth.NewPasswordHasher(config.PasswordHashConfig{Algorithm: "bcrypt", BcryptCost: 4, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := NewRouter(cfg, openapi.Spec(), Deps{
+		Users:       service.NewUserService(cfg, repository.NewMemoryRepository(), policy, hasher),
+		Webhooks:    service.NewWebhookService(hooks, cfg.Webhooks),
+		Idempotency: middleware.NewIdempotency(keys, time.Hour),
+		Broker:      stream.NewBroker(cfg.Stream),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func post(router http.Handler, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, "key-1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebhookSecretIsNotStoredForIdempotency(t *testing.T) {
+	keys, hooks := &keyStore{}, &webhookStore{}
+	router := newTestRouter(t, keys, hooks)
+
+	rec := post(router, "/v1/webhooks", "admin-token", `{"url":"https://hooks.example.com/users","event_types":["user.created"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create webhook = %d %s, want 201", rec.Code, rec.Body)
+	}
+	if len(hooks.created) != 1 || !strings.Contains(rec.Body.String(), hooks.created[0].Secret) {
+		t.Fatalf("response %s does not carry the new secret", rec.Body)
+	}
+	if len(keys.claimed) != 0 || len(keys.completed) != 0 {
+		t.Errorf("webhook create used the idempotency store: %d claimed, %d stored", len(keys.claimed), len(keys.completed))
+	}
+
+	// keys still work where responses hold no secrets
+	rec = post(router, "/v1/users", "", `{"username":"ada","email":"ada@example.com","password":"Analytical-Engine-1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create user = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if len(keys.completed) != 1 {
+		t.Errorf("create user stored %d idempotency records, want 1", len(keys.completed))
+	}
+}
+
+func TestImportTakesNoIdempotencyKey(t *testing.T) {
+	keys := &keyStore{}
+	router := newTestRouter(t, keys, &webhookStore{})
+	rec := post(router, "/v1/users", "", `{"username":"ada","email":"ada@example.com","password":"Analytical-Engine-1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create user = %d %s, want 200", rec.Code, rec.Body)
+	}
+	rec = post(router, "/v1/auth/login", "", `{"email":"ada@example.com","password":"Analytical-Engine-1"}`)
+	var login model.LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &login); err != nil || login.Token == "" {
+		t.Fatalf("login = %d %s", rec.Code, rec.Body)
+	}
+
+	// uploads may be larger than the middleware buffers, so the key is ignored
+	req := httptest.NewRequest(http.MethodPost, "/v1/users/import", strings.NewReader("email,username\ngrace@example.com,grace\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	req.Header.Set(middleware.IdempotencyKeyHeader, "import-1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if len(keys.claimed) != 1 {
+		t.Errorf("got %d idempotency claims, want only the one for creating the user", len(keys.claimed))
+	}
+}
\ No newline at end of file
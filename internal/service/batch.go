@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"user-management/internal/errors"
+	"user-management/internal/events"
+	"user-management/internal/model"
+	"user-management/internal/repository"
+)
+
+// ErrBatchAborted is reported for operations that were valid but not applied
+// because another operation of an all-or-nothing batch failed.
+var ErrBatchAborted = stderrors.New("not applied because another operation in the batch failed")
+
+// BatchResult is the outcome of one operation. User is set for successful
+// creates and updates.
+type BatchResult struct {
+	Op   string
+	User *model.User
+	Err  error
+}
+
+// batchItem carries an operation through the checks and the write.
+type batchItem struct {
+	index    int
+	op       model.BatchOperation
+	user     model.User
+	existing *model.User
+}
+
+// Batch applies ops and reports a result for each. Every operation goes
+// through the same checks as its single-request counterpart. Creates are
+// checked for existing emails and usernames in one query, hashed on a bounded
+// worker pool and inserted with multi-row inserts; they are applied before
+// the updates and deletes, which follow in request order.
+//
+// In all-or-nothing mode nothing is written unless every operation passes its
+// checks, and everything runs in one transaction. In best-effort mode failed
+// operations are skipped and the rest are applied.
+func (s *UserService) Batch(mode string, ops []model.BatchOperation) ([]BatchResult, error) {
+	if mode == "" {
+		mode = model.BatchAllOrNothing
+	}
+	if mode != model.BatchAllOrNothing && mode != model.BatchBestEffort {
+		return nil, errors.NewValidationError("mode", "must be all_or_nothing or best_effort")
+	}
+	if len(ops) == 0 {
+		return nil, errors.NewValidationError("operations", "must contain at least one operation")
+	}
+	if limit := s.cfg.Batch.MaxOperations; limit > 0 && len(ops) > limit {
+		return nil, errors.NewValidationError("operations", fmt.Sprintf("must contain at most %d operations", limit))
+	}
+	atomic := mode == model.BatchAllOrNothing
+
+	results := make([]BatchResult, len(ops))
+	var creates, changes []*batchItem
+	for i, op := range ops {
+		results[i].Op = op.Op
+		item := &batchItem{index: i, op: op}
+		if err := checkOperation(op); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if op.User != nil {
+			item.user = op.User.ToUser()
+		}
+		if op.Op == model.BatchCreate {
+			creates = append(creates, item)
+		} else {
+			changes = append(changes, item)
+		}
+	}
+
+	creates = s.checkCreates(creates, results)
+	for _, item := range changes {
+		switch item.op.Op {
+		case model.BatchUpdate:
+			existing, err := s.prepareUpdate(item.op.ID, &item.user)
+			if err != nil {
+				results[item.index].Err = err
+				continue
+			}
+			item.existing = existing
+		case model.BatchDelete:
+			if !s.repo.ExistsByID(item.op.ID) {
+				results[item.index].Err = errors.NewNotFoundError(item.op.ID, "no user with the given id")
+			}
+		}
+	}
+	if atomic && failed(results) {
+		return abortRest(results), nil
+	}
+
+	for i, err := range s.hashPasswords(creates) {
+		if err != nil {
+			results[creates[i].index].Err = err
+		}
+	}
+	if atomic && failed(results) {
+		return abortRest(results), nil
+	}
+	creates = pending(creates, results)
+	changes = pending(changes, results)
+
+	if atomic {
+		s.applyAtomic(creates, changes, results)
+	} else {
+		s.applyBestEffort(creates, changes, results)
+	}
+	for _, item := range creates {
+		if results[item.index].Err == nil {
+			results[item.index].User = &item.user
+		}
+	}
+	for _, item := range changes {
+		if results[item.index].Err == nil && item.op.Op == model.BatchUpdate {
+			item.user.ID, item.user.IsActive = item.op.ID, item.existing.IsActive
+			results[item.index].User = &item.user
+		}
+	}
+	return results, nil
+}
+
+func checkOperation(op model.BatchOperation) error {
+	switch op.Op {
+	case model.BatchCreate:
+		if op.User == nil {
+			return errors.NewValidationError("user", "is required for create")
+		}
+	case model.BatchUpdate:
+		if op.User == nil {
+			return errors.NewValidationError("user", "is required for update")
+		}
+		if op.ID <= 0 {
+			return errors.NewValidationError("id", "is required for update")
+		}
+	case model.BatchDelete:
+		if op.ID <= 0 {
+			return errors.NewValidationError("id", "is required for delete")
+		}
+	default:
+		return errors.NewValidationError("op", "must be create, update or delete")
+	}
+	return nil
+}
+
+// checkCreates validates the creates, rejects emails and usernames that are
+// taken or repeated within the batch, and returns the ones that passed.
+func (s *UserService) checkCreates(creates []*batchItem, results []BatchResult) []*batchItem {
+	var valid []*batchItem
+	for _, item := range creates {
+		item.user.IsActive = true
+		s.normalizeUser(&item.user)
+		violations := s.validateUser(item.user)
+		if item.user.Password != "" {
+			violations = append(violations, s.policy.Check(item.user.Password, item.user.Username, item.user.Email)...)
+		}
+		if len(violations) > 0 {
+			results[item.index].Err = errors.NewFieldValidationError(violations...)
+			continue
+		}
+		valid = append(valid, item)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+
+	emails := make([]string, len(valid))
+	usernames := make([]string, len(valid))
+	for i, item := range valid {
+		emails[i], usernames[i] = item.user.Email, item.user.Username
+	}
+	takenEmails, err := s.repo.ExistingEmails(emails)
+	if err != nil {
+		return failAll(valid, results, err)
+	}
+	takenUsernames, err := s.repo.ExistingUsernames(usernames)
+	if err != nil {
+		return failAll(valid, results, err)
+	}
+
+	var unique []*batchItem
+	for _, item := range valid {
+		email, username := strings.ToLower(item.user.Email), strings.ToLower(item.user.Username)
+		switch {
+		case takenEmails[email]:
+			results[item.index].Err = errors.NewDuplicateError("email", item.user.Email)
+		case takenUsernames[username]:
+			results[item.index].Err = errors.NewDuplicateError("username", item.user.Username)
+		default:
+			// later creates in the same batch conflict with this one
+			takenEmails[email], takenUsernames[username] = true, true
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+// hashPasswords hashes the create passwords on at most HashWorkers goroutines
+// and returns an error per item.
+func (s *UserService) hashPasswords(creates []*batchItem) []error {
+	errs := make([]error, len(creates))
+	workers := max(s.cfg.Batch.HashWorkers, 1)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(creates)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hashed, err := s.hasher.Hash(creates[i].user.Password)
+				if err != nil {
+					slog.Error("password hashing failed", "error", err, "email", creates[i].user.Email)
+					errs[i] = fmt.Errorf("error while encrypting password %w", err)
+					continue
+				}
+				creates[i].user.Password = hashed
+			}
+		}()
+	}
+	for i := range creates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+// applyAtomic writes everything in one transaction. When a write fails the
+// operation that caused it gets the error and the others are aborted.
+func (s *UserService) applyAtomic(creates, changes []*batchItem, results []BatchResult) {
+	var culprit []*batchItem
+	err := s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		culprit = creates
+		if err := insertUsers(tx, creates); err != nil {
+			culprit = duplicateCulprit(creates, err)
+			return err
+		}
+		for _, item := range changes {
+			culprit = []*batchItem{item}
+			if err := applyChange(tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	slog.Warn("batch rolled back", "error", err)
+	for _, item := range culprit {
+		results[item.index].Err = err
+	}
+	abortRest(results)
+}
+
+// applyBestEffort inserts the creates together and falls back to one
+// transaction per user when that fails, so a single conflict only fails its
+// own row. Updates and deletes each run in their own transaction.
+func (s *UserService) applyBestEffort(creates, changes []*batchItem, results []BatchResult) {
+	if len(creates) > 0 {
+		err := s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+			return insertUsers(tx, creates)
+		})
+		if err != nil {
+			slog.Warn("bulk insert failed, inserting users one by one", "error", err)
+			for _, item := range creates {
+				results[item.index].Err = s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+					return insertUser(tx, &item.user)
+				})
+			}
+		}
+	}
+	for _, item := range changes {
+		results[item.index].Err = s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+			return applyChange(tx, item)
+		})
+	}
+}
+
+func insertUsers(tx repository.Repos, creates []*batchItem) error {
+	if len(creates) == 0 {
+		return nil
+	}
+	users := make([]*model.User, len(creates))
+	for i, item := range creates {
+		users[i] = &item.user
+	}
+	if err := tx.Users.CreateMany(users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := publish(tx, u.ID, events.UserCreated{User: model.NewUserResponse(u)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyChange(tx repository.Repos, item *batchItem) error {
+	if item.op.Op == model.BatchUpdate {
+		return applyUpdate(tx, item.op.ID, item.user, item.existing)
+	}
+	return applyDelete(tx, item.op.ID)
+}
+
+// duplicateCulprit narrows a failed bulk insert down to the create holding
+// the duplicate value, or all of them when it cannot tell.
+func duplicateCulprit(creates []*batchItem, err error) []*batchItem {
+	var dup *errors.DuplicateError
+	if stderrors.As(err, &dup) {
+		for _, item := range creates {
+			if strings.EqualFold(item.user.Email, dup.Value) || strings.EqualFold(item.user.Username, dup.Value) {
+				return []*batchItem{item}
+			}
+		}
+	}
+	return creates
+}
+
+func failAll(items []*batchItem, results []BatchResult, err error) []*batchItem {
+	for _, item := range items {
+		results[item.index].Err = err
+	}
+	return nil
+}
+
+func failed(results []BatchResult) bool {
+	for _, r := range results {
+		if r.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// abortRest marks every operation without an error as aborted.
+func abortRest(results []BatchResult) []BatchResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = ErrBatchAborted
+		}
+	}
+	return results
+}
+
+func pending(items []*batchItem, results []BatchResult) []*batchItem {
+	var out []*batchItem
+	for _, item := range items {
+		if results[item.index].Err == nil {
+			out = append(out, item)
+		}
+	}
+	return out
+}
\ No newline at end of file
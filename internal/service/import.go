@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"user-management/internal/errors"
+	"user-management/internal/imports"
+	"user-management/internal/model"
+)
+
+// importChunk is how many rows are checked and written together.
+const importChunk = 500
+
+type ImportOptions struct {
+	DryRun bool
+	// OnDuplicate is model.OnDuplicateSkip or model.OnDuplicateUpsert and
+	// decides what happens to rows whose email already exists.
+	OnDuplicate string
+}
+
+// ImportUsers reads every row and records its outcome on job. New users go
+// through the same checks as CreateUser and are written like a best-effort
+// batch; rows for existing emails are skipped or, with upsert, change only
+// the columns the file has, as PatchUser does, and go through the checks of
+// UpdateUser. The password column is ignored for updates. A dry run does all
+// the checks and writes nothing.
+func (s *UserService) ImportUsers(rows imports.Reader, opts ImportOptions, job *imports.Job) error {
+	job.Start()
+	err := s.importRows(rows, opts, job)
+	job.Finish(err)
+	return err
+}
+
+func (s *UserService) importRows(rows imports.Reader, opts ImportOptions, job *imports.Job) error {
+	// emails and usernames seen earlier in the file, lowercased
+	seen := map[string]bool{}
+	var chunk []imports.Row
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read import file %w", err)
+		}
+		if row.Err != nil {
+			job.Malformed(row.Line, row.Err)
+			continue
+		}
+		chunk = append(chunk, row)
+		if len(chunk) == importChunk {
+			if err := s.importChunk(chunk, opts, job, seen); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	return s.importChunk(chunk, opts, job, seen)
+}
+
+func (s *UserService) importChunk(rows []imports.Row, opts ImportOptions, job *imports.Job, seen map[string]bool) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	items := make([]*batchItem, len(rows))
+	emails := make([]string, len(rows))
+	for i, row := range rows {
+		items[i] = &batchItem{index: i, user: row.User.ToUser()}
+		s.normalizeUser(&items[i].user)
+		emails[i] = items[i].user.Email
+	}
+	existing, err := s.repo.ExistingEmails(emails)
+	if err != nil {
+		return err
+	}
+
+	results := make([]BatchResult, len(rows))
+	skipped := make([]bool, len(rows))
+	var creates, updates []*batchItem
+	for _, item := range items {
+		user := item.user
+		email, username := "email:"+strings.ToLower(user.Email), "username:"+strings.ToLower(user.Username)
+		switch {
+		case seen[email]:
+			results[item.index].Err = errors.NewDuplicateError("email", user.Email+" (earlier in the file)")
+		case user.Username != "" && seen[username]:
+			results[item.index].Err = errors.NewDuplicateError("username", user.Username+" (earlier in the file)")
+		case existing[strings.ToLower(user.Email)]:
+			if opts.OnDuplicate != model.OnDuplicateUpsert {
+				skipped[item.index] = true
+				continue
+			}
+			item.op.Op = model.BatchUpdate
+			updates = append(updates, item)
+		default:
+			item.op.Op = model.BatchCreate
+			creates = append(creates, item)
+		}
+		seen[email] = true
+		if user.Username != "" {
+			seen[username] = true
+		}
+	}
+
+	creates = s.checkCreates(creates, results)
+	for _, item := range updates {
+		current, err := s.repo.GetByEmail(item.user.Email)
+		if err != nil {
+			results[item.index].Err = err
+			continue
+		}
+		item.op.ID = current.ID
+		item.user = applyPatch(current, rows[item.index].Patch)
+		if item.existing, err = s.prepareUpdate(current.ID, &item.user); err != nil {
+			results[item.index].Err = err
+		}
+	}
+	updates = pending(updates, results)
+
+	if !opts.DryRun {
+		for i, err := range s.hashPasswords(creates) {
+			if err != nil {
+				results[creates[i].index].Err = err
+			}
+		}
+		creates = pending(creates, results)
+		s.applyBestEffort(creates, updates, results)
+	}
+
+	for i, result := range results {
+		switch {
+		case skipped[i]:
+			job.Skipped()
+		case result.Err != nil:
+			job.Fail(rows[i].Line, items[i].user.Email, result.Err)
+		case items[i].op.Op == model.BatchCreate:
+			job.Created()
+		default:
+			job.Updated()
+		}
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"user-management/internal/imports"
+	"user-management/internal/model"
+)
+
+func upsert(t *testing.T, s *UserService, rows imports.Reader) model.ImportJob {
+	t.Helper()
+	job := imports.NewJobs(time.Hour).Create("", false, model.OnDuplicateUpsert)
+	if err := s.ImportUsers(rows, ImportOptions{OnDuplicate: model.OnDuplicateUpsert}, job); err != nil {
+		t.Fatal(err)
+	}
+	return job.Snapshot()
+}
+
+func importCSV(t *testing.T, s *UserService, csv string) model.ImportJob {
+	t.Helper()
+	rows, err := imports.NewCSVReader(strings.NewReader(csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return upsert(t, s, rows)
+}
+
+func TestImportUpsertKeepsMissingColumns(t *testing.T) {
+	s, store := newTestService(t)
+	user := createTestUser(t, s)
+
+	// no name column: the name is left alone
+	job := importCSV(t, s, "email,username\nada@example.com,countess\n")
+	if job.Updated != 1 || job.Failed != 0 {
+		t.Fatalf("job = %+v, want one update", job)
+	}
+	got, err := store.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "countess" || got.Name != "Ada Lovelace" {
+		t.Errorf("got username %q and name %q, want countess and the old name", got.Username, got.Name)
+	}
+
+	// no username column: the row is not rejected for a missing username
+	job = importCSV(t, s, "email,name\nADA@example.com,Ada King\n")
+	if job.Updated != 1 || job.Failed != 0 {
+		t.Fatalf("job = %+v, want one update", job)
+	}
+	got, err = store.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "countess" || got.Name != "Ada King" {
+		t.Errorf("got username %q and name %q, want countess and Ada King", got.Username, got.Name)
+	}
+}
+
+func TestImportUpsertAppliesNDJSONKeys(t *testing.T) {
+	s, store := newTestService(t)
+	user := createTestUser(t, s)
+
+	rows := imports.NewNDJSONReader(strings.NewReader(`{"email":"ada@example.com","name":"Ada King"}` + "\n"))
+	if job := upsert(t, s, rows); job.Updated != 1 || job.Failed != 0 {
+		t.Fatalf("job = %+v, want one update", job)
+	}
+	got, err := store.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "ada" || got.Name != "Ada King" {
+		t.Errorf("got username %q and name %q, want ada and Ada King", got.Username, got.Name)
+	}
+}
\ No newline at end of file
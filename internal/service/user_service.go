@@ -1,129 +1,537 @@
 package service
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 	"user-management/internal/auth"
 	"user-management/internal/config"
 	"user-management/internal/errors"
+	"user-management/internal/events"
 	"user-management/internal/model"
 	"user-management/internal/repository"
-
-	"golang.org/x/crypto/bcrypt"
+	"user-management/internal/validation"
 )
 
 type UserService struct {
-	cfg  *config.Config
-	repo repository.UserRepo
+	cfg    *config.Config
+	repo   repository.Store
+	policy *auth.PasswordPolicy
+	hasher auth.PasswordHasher
 }
 
-func NewUserService(cfg *config.Config, repo repository.UserRepo) *UserService {
+func NewUserService(cfg *config.Config, repo repository.Store, policy *auth.PasswordPolicy, hasher auth.PasswordHasher) *UserService {
 	return &UserService{
-		cfg:  cfg,
-		repo: repo}
+		cfg:    cfg,
+		repo:   repo,
+		policy: policy,
+		hasher: hasher}
+}
+
+func (s *UserService) GetAllUsers(filter model.UserFilter) ([]model.User, error) {
+	return s.repo.GetAll(filter)
 }
 
-func (s *UserService) GetAllUsers() ([]model.User, error) {
-	return s.repo.GetAll()
+// ExportUsers streams the users matching filter to fn without loading them all.
+func (s *UserService) ExportUsers(filter model.UserFilter, fn func(model.User) error) error {
+	return s.repo.StreamAll(filter, fn)
 }
 
 func (s *UserService) GetUser(id int) (*model.User, error) {
 	return s.repo.GetByID(id)
 }
 
+// GetUsersByIDs looks up several users in one query. Ids without a user are
+// left out of the result.
+func (s *UserService) GetUsersByIDs(ids []int) ([]model.User, error) {
+	return s.repo.GetByIDs(ids)
+}
+
+// errPageFull stops the stream once a page has been read.
+var errPageFull = stderrors.New("page full")
+
+// ListUsersPage returns up to limit users matching filter in id order,
+// starting after filter.AfterID, and whether more users follow.
+func (s *UserService) ListUsersPage(filter model.UserFilter, limit int) ([]model.User, bool, error) {
+	users := make([]model.User, 0, limit)
+	more := false
+	err := s.repo.StreamAll(filter, func(u model.User) error {
+		if len(users) == limit {
+			more = true
+			return errPageFull
+		}
+		users = append(users, u)
+		return nil
+	})
+	if err != nil && err != errPageFull {
+		return nil, false, err
+	}
+	return users, more, nil
+}
+
 func (s *UserService) CreateUser(user *model.User) error {
-	if err := s.validateUser(*user); err != nil {
+	user.IsActive = true
+	if err := s.prepareCreate(user); err != nil {
+		return err
+	}
+	return s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		return insertUser(tx, user)
+	})
+}
+
+// prepareCreate normalizes and checks a new user and hashes its password.
+func (s *UserService) prepareCreate(user *model.User) error {
+	s.normalizeUser(user)
+	violations := s.validateUser(*user)
+	if user.Password != "" {
+		violations = append(violations, s.policy.Check(user.Password, user.Username, user.Email)...)
+	}
+	if len(violations) > 0 {
+		err := errors.NewFieldValidationError(violations...)
 		slog.Warn("user validation failed", "error", err, "email", user.Email)
 		return err
 	}
+	// fast path only; concurrent creates are caught by the unique indexes
 	if s.repo.ExistsByEmail(user.Email) {
 		slog.Warn("Email already exists", "email", user.Email)
-		return errors.NewDuplicateError(user.ID, "already existed with the email")
+		return errors.NewDuplicateError("email", user.Email)
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if s.repo.ExistsByUsername(user.Username) {
+		slog.Warn("Username already exists", "username", user.Username)
+		return errors.NewDuplicateError("username", user.Username)
+	}
+	hashed, err := s.hasher.Hash(user.Password)
 	if err != nil {
 		slog.Error("password hashing failed", "error", err, "email", user.Email)
 		return fmt.Errorf("error while encrypting password %w", err)
 	}
-	user.Password = string(hashed)
-	return s.repo.Create(user)
+	user.Password = hashed
+	return nil
 }
 
-func (s *UserService) Login(email, password string) (string, error) {
+// insertUser stores a validated user whose password is already hashed,
+// together with its first history entry and creation event.
+func insertUser(tx repository.Repos, user *model.User) error {
+	if err := tx.Users.Create(user); err != nil {
+		return err
+	}
+	if err := tx.Users.RecordPasswordChange(user.ID, user.Password); err != nil {
+		slog.Error("unable to record initial password", "error", err, "user_id", user.ID)
+		return err
+	}
+	return publish(tx, user.ID, events.UserCreated{User: model.NewUserResponse(user)})
+}
+
+// Login returns a token for the user. When the password is older than the
+// configured maximum age the token only allows changing the password, and
+// changeRequired is true.
+func (s *UserService) Login(email, password string) (token string, changeRequired bool, err error) {
+	email = s.normalizeEmail(email)
 	u, err := s.CheckPassword(email, password)
 	if err != nil {
 		slog.Error("Invalid password.Try again","error",err)
-		return "", err
+		// unknown email and wrong password look the same to the caller
+		var notFound *errors.NotFoundError
+		if stderrors.As(err, &notFound) || stderrors.Is(err, auth.ErrPasswordMismatch) {
+			return "", false, auth.ErrInvalidCredentials
+		}
+		return "", false, err
+	}
+	if !u.IsActive {
+		slog.Warn("login rejected for deactivated user", "user_id", u.ID)
+		return "", false, auth.ErrInvalidCredentials
 	}
 	slog.Info("Password matched!!","user_email",email)
-	token, err := auth.GenerateToken(u, s.cfg)
+	s.rehashIfNeeded(u, password)
+	if s.passwordExpired(u) {
+		slog.Warn("password expired, issuing password change token", "user_id", u.ID)
+		token, err = auth.GeneratePasswordChangeToken(u, s.cfg)
+		changeRequired = true
+	} else {
+		token, err = auth.GenerateToken(u, s.cfg)
+	}
 	if err != nil {
 		slog.Error("token generation failed", "error", err)
-		return "", err
+		return "", false, err
 	}
-	return token, nil
+	return token, changeRequired, nil
 }
 
 func (s *UserService) UpdateUser(id int, user model.User) error {
+	existingUser, err := s.prepareUpdate(id, &user)
+	if err != nil {
+		return err
+	}
+	return s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		return applyUpdate(tx, id, user, existingUser)
+	})
+}
+
+// prepareUpdate normalizes and checks user as the new state of id and
+// returns the current state.
+func (s *UserService) prepareUpdate(id int, user *model.User) (*model.User, error) {
 	if id < 0 {
-		return errors.NewValidationError(id, "id cannot be negative")
+		return nil, errors.NewValidationError("id", "id cannot be negative")
+	}
+	if user.Password != "" {
+		return nil, errors.NewValidationError("password", "use POST /users/me/password to change the password")
 	}
 	existingUser, err := s.repo.GetByID(id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	user.Password = existingUser.Password
+	s.normalizeUser(user)
+	if violations := s.validateUser(*user); len(violations) > 0 {
+		err := errors.NewFieldValidationError(violations...)
+		slog.Warn("user validation failed", "error", err, "user_id", id)
+		return nil, err
 	}
 
-	// Check if username is changing and if new username already exists
-	if user.Username != existingUser.Username {
+	// Check if username is changing and if new username already exists.
+	// A change of case only is not a conflict with the user's own row.
+	if !strings.EqualFold(user.Username, existingUser.Username) {
 		if s.repo.ExistsByUsername(user.Username) {
-			return errors.NewDuplicateError("username", user.Username)
+			return nil, errors.NewDuplicateError("username", user.Username)
 		}
 	}
 
 	// Check if email is changing and if new email already exists
-	if user.Email != existingUser.Email {
+	if !strings.EqualFold(user.Email, existingUser.Email) {
 		if s.repo.ExistsByEmail(user.Email) {
-			return errors.NewDuplicateError("email", user.Email)
+			return nil, errors.NewDuplicateError("email", user.Email)
 		}
 	}
-	return s.repo.Update(id, user)
+	return existingUser, nil
 }
-func (s *UserService) DeleteUser(id int) error {
-	if id < 0 {
-		return errors.NewValidationError(id, "id cannot be negative")
+
+func applyUpdate(tx repository.Repos, id int, user model.User, existingUser *model.User) error {
+	if err := tx.Users.Update(id, user); err != nil {
+		return err
+	}
+	user.ID, user.IsActive = id, existingUser.IsActive
+	changes := []events.Payload{events.UserUpdated{User: model.NewUserResponse(&user)}}
+	if user.Email != existingUser.Email {
+		changes = append(changes, events.UserEmailChanged{OldEmail: existingUser.Email, NewEmail: user.Email})
 	}
-	return s.repo.Delete(id)
+	return publish(tx, id, changes...)
 }
 
-func (s *UserService) validateUser(user model.User) error {
-	if user.ID < 0 {
-		return errors.NewValidationError(user.ID, "id cannot be negative")
+// SetActive activates or deactivates a user. Deactivated users cannot log
+// in and their existing tokens stop working.
+func (s *UserService) SetActive(id int, active bool) error {
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if user.IsActive == active {
+		return nil
 	}
-	username := strings.TrimSpace(user.Username)
-	name := strings.TrimSpace(user.Name)
-	email := strings.TrimSpace(user.Email)
+	return s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		return applyActive(tx, user, active)
+	})
+}
 
-	if username == "" {
-		return errors.NewValidationError(user.Username, "username can't be null")
+// ReplaceUser is UpdateUser for provisioning systems, which send whether the
+// user is active along with the other attributes. Both changes are written in
+// one transaction; a nil active is left as it is.
+func (s *UserService) ReplaceUser(id int, user model.User, active *bool) error {
+	existingUser, err := s.prepareUpdate(id, &user)
+	if err != nil {
+		return err
 	}
-	if name == username {
-		return errors.NewValidationError(user.Username, "username can't be same as name")
+	return s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		if err := applyUpdate(tx, id, user, existingUser); err != nil {
+			return err
+		}
+		if active == nil || *active == existingUser.IsActive {
+			return nil
+		}
+		user.ID = id
+		return applyActive(tx, &user, *active)
+	})
+}
+
+func applyActive(tx repository.Repos, user *model.User, active bool) error {
+	if err := tx.Users.SetActive(user.ID, active); err != nil {
+		return err
 	}
-	if email == "" {
-		return errors.NewValidationError(user.Email, "mail can't be null")
+	if !active {
+		return publish(tx, user.ID, events.UserDeactivated{})
 	}
+	user.IsActive = true
+	return publish(tx, user.ID, events.UserUpdated{User: model.NewUserResponse(user)})
+}
+
+// ProvisionUser creates a user on behalf of a provisioning system. Those
+// users usually sign in elsewhere, so a missing password is replaced by a
+// random one that is never shown.
+func (s *UserService) ProvisionUser(user *model.User, active bool) error {
 	if user.Password == "" {
-		return errors.NewValidationError(user.Password, "password can't be null")
+		password, err := s.policy.Generate()
+		if err != nil {
+			return err
+		}
+		user.Password = password
+	}
+	// created in its final state, so an inactive user is never active and
+	// only user.created is published
+	user.IsActive = active
+	if err := s.prepareCreate(user); err != nil {
+		return err
+	}
+	return s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		return insertUser(tx, user)
+	})
+}
+
+// PatchUser applies only the fields set in patch and runs the same checks as UpdateUser.
+func (s *UserService) PatchUser(id int, patch model.UserPatch) error {
+	existingUser, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return s.UpdateUser(id, applyPatch(existingUser, patch))
+}
+
+// applyPatch returns the updatable fields of existingUser with the ones set
+// in patch replaced.
+func applyPatch(existingUser *model.User, patch model.UserPatch) model.User {
+	user := model.User{
+		Username:   existingUser.Username,
+		Email:      existingUser.Email,
+		Name:       existingUser.Name,
+		GivenName:  existingUser.GivenName,
+		FamilyName: existingUser.FamilyName,
+	}
+	if patch.Username != nil {
+		user.Username = *patch.Username
+	}
+	if patch.Email != nil {
+		user.Email = *patch.Email
+	}
+	if patch.Name != nil {
+		// a new formatted name is split again
+		user.Name, user.GivenName, user.FamilyName = *patch.Name, "", ""
+	}
+	if patch.GivenName != nil || patch.FamilyName != nil {
+		// name is joined again from the new parts
+		user.Name = ""
+	}
+	if patch.GivenName != nil {
+		user.GivenName = *patch.GivenName
+	}
+	if patch.FamilyName != nil {
+		user.FamilyName = *patch.FamilyName
+	}
+	return user
+}
+
+// ChangePassword replaces the password after verifying the current one.
+// Every token issued before the change stops working, so a fresh token is
+// returned for the caller.
+func (s *UserService) ChangePassword(id int, currentPassword, newPassword string) (string, error) {
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+	if err := s.hasher.Verify(user.Password, currentPassword); err != nil {
+		slog.Warn("change password rejected, current password mismatch", "user_id", id)
+		return "", errors.NewValidationError("current_password", "current password is incorrect")
+	}
+	hashed, err := s.preparePassword(user, newPassword, user.Username, user.Email)
+	if err != nil {
+		return "", err
+	}
+	err = s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		if err := tx.Users.UpdatePassword(id, hashed); err != nil {
+			return err
+		}
+		if err := tx.Users.RecordPasswordChange(id, hashed); err != nil {
+			slog.Error("unable to record password change", "error", err, "user_id", id)
+			return err
+		}
+		return publish(tx, id, events.UserPasswordChanged{})
+	})
+	if err != nil {
+		return "", err
+	}
+	slog.Info("password changed, other sessions revoked", "user_id", id)
+	return auth.GenerateToken(user, s.cfg)
+}
+
+// IntrospectToken validates a token the way the auth middleware does,
+// including revocation and deactivation, and returns its claims.
+func (s *UserService) IntrospectToken(token string) (*model.AccessClaims, error) {
+	claims, err := auth.ValidateToken(token, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CheckSession(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CheckSession rejects tokens of deactivated users and tokens issued before
+// the user's last password change.
+func (s *UserService) CheckSession(claims *model.AccessClaims) error {
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return errors.NewValidationError("sub", "token subject is not a user id")
+	}
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return auth.ErrSessionRevoked
+	}
+	// iat has one second resolution
+	if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(user.PasswordChangedAt.Truncate(time.Second)) {
+		return auth.ErrSessionRevoked
 	}
 	return nil
 }
 
+// preparePassword runs a new password through the policy and reuse checks
+// and returns its hash.
+func (s *UserService) preparePassword(existing *model.User, plain, username, email string) (string, error) {
+	if err := s.policy.Validate(plain, username, email); err != nil {
+		slog.Warn("password policy check failed", "error", err, "user_id", existing.ID)
+		return "", err
+	}
+	if err := s.checkPasswordReuse(existing, plain); err != nil {
+		slog.Warn("password reuse rejected", "user_id", existing.ID)
+		return "", err
+	}
+	hashed, err := s.hasher.Hash(plain)
+	if err != nil {
+		slog.Error("password hashing failed", "error", err, "user_id", existing.ID)
+		return "", fmt.Errorf("error while encrypting password %w", err)
+	}
+	return hashed, nil
+}
+
+// checkPasswordReuse rejects plain if it matches the current password or any
+// of the last HistorySize passwords.
+func (s *UserService) checkPasswordReuse(existing *model.User, plain string) error {
+	size := s.cfg.PasswordPolicy.HistorySize
+	if size <= 0 {
+		return nil
+	}
+	history, err := s.repo.GetPasswordHistory(existing.ID, size)
+	if err != nil {
+		return fmt.Errorf("unable to load password history %w", err)
+	}
+	for _, hash := range append([]string{existing.Password}, history...) {
+		if s.hasher.Verify(hash, plain) == nil {
+			return errors.NewFieldValidationError(errors.FieldViolation{
+				Field:   "password",
+				Code:    "reused",
+				Message: fmt.Sprintf("must not match any of the last %d passwords", size),
+			})
+		}
+	}
+	return nil
+}
+
+func (s *UserService) passwordExpired(user *model.User) bool {
+	maxAge := s.cfg.PasswordPolicy.MaxAge
+	return maxAge > 0 && time.Since(user.PasswordChangedAt) > maxAge
+}
+func (s *UserService) DeleteUser(id int) error {
+	if id < 0 {
+		return errors.NewValidationError("id", "id cannot be negative")
+	}
+	return s.repo.WithTx(context.Background(), func(tx repository.Repos) error {
+		return applyDelete(tx, id)
+	})
+}
+
+func applyDelete(tx repository.Repos, id int) error {
+	if err := tx.Users.Delete(id); err != nil {
+		return err
+	}
+	return publish(tx, id, events.UserDeleted{UserID: id})
+}
+
+// publish writes events to the outbox of the surrounding transaction, so they
+// are stored if and only if the change they describe commits.
+func publish(tx repository.Repos, userID int, payloads ...events.Payload) error {
+	for _, p := range payloads {
+		e, err := events.New(userID, p)
+		if err != nil {
+			return err
+		}
+		if err := tx.Outbox.Append(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateUser collects the tag rules on model.User plus the rules that
+// span several fields.
+func (s *UserService) validateUser(user model.User) []errors.FieldViolation {
+	violations := validation.Struct(user)
+	if user.ID < 0 {
+		violations = append(violations, errors.FieldViolation{Field: "id", Code: "invalid", Message: "id cannot be negative"})
+	}
+	if user.Username != "" && user.Name == user.Username {
+		violations = append(violations, errors.FieldViolation{Field: "username", Code: "same_as_name", Message: "username can't be same as name"})
+	}
+	return violations
+}
+
+// normalizeUser brings the identifying fields into their canonical form
+// before they are validated, compared or stored.
+func (s *UserService) normalizeUser(user *model.User) {
+	user.Username = validation.NormalizeUsername(user.Username)
+	user.Email = s.normalizeEmail(user.Email)
+	user.Name = strings.TrimSpace(user.Name)
+	user.GivenName = strings.TrimSpace(user.GivenName)
+	user.FamilyName = strings.TrimSpace(user.FamilyName)
+	// v2 writes given and family names, everything else writes name
+	if user.GivenName != "" || user.FamilyName != "" {
+		user.Name = model.JoinName(user.GivenName, user.FamilyName)
+	} else {
+		user.GivenName, user.FamilyName = model.SplitName(user.Name)
+	}
+}
+
+func (s *UserService) normalizeEmail(email string) string {
+	return validation.NormalizeEmail(email, s.cfg.LowercaseEmailLocalPart)
+}
+
 func (s *UserService) CheckPassword(email, plainPassword string) (*model.User, error) {
 	user, err := s.repo.GetByEmail(email)
 	if err != nil {
 		slog.Error("password check failed (user lookup)", "error", err, "email", email)
 		return nil, err
 	}
-	return user, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(plainPassword))
+	return user, s.hasher.Verify(user.Password, plainPassword)
+}
+
+// rehashIfNeeded upgrades a stored hash to the configured algorithm and cost
+// after a successful login. Failures are logged and never block the login.
+func (s *UserService) rehashIfNeeded(user *model.User, plainPassword string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+	hashed, err := s.hasher.Hash(plainPassword)
+	if err != nil {
+		slog.Error("password rehash failed", "error", err, "user_id", user.ID)
+		return
+	}
+	if err := s.repo.UpdatePassword(user.ID, hashed); err != nil {
+		slog.Error("unable to store rehashed password", "error", err, "user_id", user.ID)
+		return
+	}
+	user.Password = hashed
+	slog.Info("password rehashed with current parameters", "user_id", user.ID)
 }
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+	"user-management/internal/auth"
+	"user-management/internal/config"
+	"user-management/internal/errors"
+	"user-management/internal/events"
+	"user-management/internal/model"
+	"user-management/internal/repository"
+)
+
+var errOutboxDown = stderrors.New("outbox unavailable")
+
+// faultyStore is a MemoryRepository whose transactions get an outbox that
+// fails once failAfter events have been appended in them. A negative
+// failAfter never fails.
+type faultyStore struct {
+	*repository.MemoryRepository
+	failAfter int
+}
+
+func (s *faultyStore) WithTx(ctx context.Context, fn func(repository.Repos) error) error {
+	return s.MemoryRepository.WithTx(ctx, func(tx repository.Repos) error {
+		if s.failAfter >= 0 {
+			tx.Outbox = &faultyOutbox{OutboxRepo: tx.Outbox, left: s.failAfter}
+		}
+		return fn(tx)
+	})
+}
+
+type faultyOutbox struct {
+	repository.OutboxRepo
+	left int
+}
+
+func (o *faultyOutbox) Append(e *events.Event) error {
+	if o.left == 0 {
+		return errOutboxDown
+	}
+	o.left--
+	return o.OutboxRepo.Append(e)
+}
+
+func newTestService(t *testing.T) (*UserService, *faultyStore) {
+	t.Helper()
+	policy, err := auth.NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher, err := auth.NewPasswordHasher(config.PasswordHashConfig{
+		Algorithm:         "bcrypt",
+		BcryptCost:        4,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &faultyStore{MemoryRepository: repository.NewMemoryRepository(), failAfter: -1}
+	cfg := &config.Config{JWTSecret: "test", JWTExpiry: time.Hour}
+	return NewUserService(cfg, store, policy, hasher), store
+}
+
+func createTestUser(t *testing.T, s *UserService) *model.User {
+	t.Helper()
+	user := &model.User{Username: "ada", Email: "ada@example.com", Password: "Analytical-Engine-1", Name: "Ada Lovelace"}
+	if err := s.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func pendingEvents(t *testing.T, store *faultyStore) []events.Event {
+	t.Helper()
+	pending, err := store.FetchPending(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending
+}
+
+func TestCreateUserRollsBackWhenOutboxFails(t *testing.T) {
+	s, store := newTestService(t)
+	store.failAfter = 0
+
+	user := &model.User{Username: "ada", Email: "ada@example.com", Password: "Analytical-Engine-1"}
+	if err := s.CreateUser(user); !stderrors.Is(err, errOutboxDown) {
+		t.Fatalf("CreateUser error = %v, want %v", err, errOutboxDown)
+	}
+	var notFound *errors.NotFoundError
+	if _, err := store.GetByEmail("ada@example.com"); !stderrors.As(err, &notFound) {
+		t.Errorf("user was stored despite the rollback, GetByEmail error = %v", err)
+	}
+	if history, _ := store.GetPasswordHistory(user.ID, 10); len(history) > 0 {
+		t.Errorf("password history was kept: %v", history)
+	}
+	if pending := pendingEvents(t, store); len(pending) > 0 {
+		t.Errorf("events were left behind: %+v", pending)
+	}
+
+	// the rolled back user does not block a retry
+	store.failAfter = -1
+	if err := s.CreateUser(&model.User{Username: "ada", Email: "ada@example.com", Password: "Analytical-Engine-1"}); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+}
+
+func TestUpdateUserRollsBackWhenLaterEventFails(t *testing.T) {
+	s, store := newTestService(t)
+	user := createTestUser(t, s)
+	before := pendingEvents(t, store)
+
+	// an email change publishes user.updated then user.email_changed; the
+	// second append fails after the first has been written
+	store.failAfter = 1
+	err := s.UpdateUser(user.ID, model.User{Username: "ada", Email: "countess@example.com", Name: "Ada King"})
+	if !stderrors.Is(err, errOutboxDown) {
+		t.Fatalf("UpdateUser error = %v, want %v", err, errOutboxDown)
+	}
+	got, err := store.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != "ada@example.com" || got.Name != "Ada Lovelace" {
+		t.Errorf("user was updated despite the rollback: %+v", got)
+	}
+	if after := pendingEvents(t, store); len(after) != len(before) {
+		t.Errorf("got %d pending events, want the %d from before the update", len(after), len(before))
+	}
+}
+
+func TestDeleteUserRollsBackWhenOutboxFails(t *testing.T) {
+	s, store := newTestService(t)
+	user := createTestUser(t, s)
+	before := pendingEvents(t, store)
+
+	store.failAfter = 0
+	if err := s.DeleteUser(user.ID); !stderrors.Is(err, errOutboxDown) {
+		t.Fatalf("DeleteUser error = %v, want %v", err, errOutboxDown)
+	}
+	if _, err := store.GetByID(user.ID); err != nil {
+		t.Errorf("user was deleted despite the rollback: %v", err)
+	}
+	if after := pendingEvents(t, store); len(after) != len(before) {
+		t.Errorf("got %d pending events, want %d", len(after), len(before))
+	}
+}
+
+func TestReplaceUserRollsBackWhenDeactivationFails(t *testing.T) {
+	s, store := newTestService(t)
+	user := createTestUser(t, s)
+
+	// the update publishes user.updated, then user.deactivated fails
+	store.failAfter = 1
+	inactive := false
+	err := s.ReplaceUser(user.ID, model.User{Username: "ada", Email: "ada@example.com", Name: "Ada King"}, &inactive)
+	if !stderrors.Is(err, errOutboxDown) {
+		t.Fatalf("ReplaceUser error = %v, want %v", err, errOutboxDown)
+	}
+	got, err := store.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Ada Lovelace" || !got.IsActive {
+		t.Errorf("user was changed despite the rollback: %+v", got)
+	}
+
+	store.failAfter = -1
+	if err := s.ReplaceUser(user.ID, model.User{Username: "ada", Email: "ada@example.com", Name: "Ada King"}, &inactive); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = store.GetByID(user.ID); got.Name != "Ada King" || got.IsActive {
+		t.Errorf("got %+v, want the new name and inactive", got)
+	}
+}
+
+func TestProvisionInactiveUserInOneStep(t *testing.T) {
+	s, store := newTestService(t)
+
+	// the only write that can fail is the single user.created event
+	store.failAfter = 0
+	user := &model.User{Username: "ada", Email: "ada@example.com"}
+	if err := s.ProvisionUser(user, false); !stderrors.Is(err, errOutboxDown) {
+		t.Fatalf("ProvisionUser error = %v, want %v", err, errOutboxDown)
+	}
+	if store.ExistsByEmail("ada@example.com") {
+		t.Fatal("user was stored despite the rollback")
+	}
+
+	store.failAfter = -1
+	user = &model.User{Username: "ada", Email: "ada@example.com"}
+	if err := s.ProvisionUser(user, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IsActive {
+		t.Error("user provisioned as inactive is active")
+	}
+	pending := pendingEvents(t, store)
+	if len(pending) != 1 || pending[0].Type != events.TypeUserCreated {
+		t.Errorf("got events %+v, want a single user.created", pending)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/errors"
+	"user-management/internal/events"
+	"user-management/internal/model"
+	"user-management/internal/repository"
+	"user-management/internal/webhooks"
+)
+
+type WebhookService struct {
+	repo repository.WebhookRepo
+	cfg  config.WebhookConfig
+}
+
+func NewWebhookService(repo repository.WebhookRepo, cfg config.WebhookConfig) *WebhookService {
+	return &WebhookService{repo: repo, cfg: cfg}
+}
+
+// CreateWebhook registers a subscription. The returned webhook carries the
+// signing secret, which is not readable afterwards.
+func (s *WebhookService) CreateWebhook(req model.CreateWebhookRequest) (*model.Webhook, error) {
+	if violations := s.validateWebhook(req.URL, req.EventTypes); len(violations) > 0 {
+		return nil, errors.NewFieldValidationError(violations...)
+	}
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("unable to generate webhook secret %w", err)
+		}
+		secret = "whsec_" + hex.EncodeToString(buf)
+	}
+	w := &model.Webhook{URL: req.URL, EventTypes: req.EventTypes, Secret: secret}
+	if err := s.repo.CreateWebhook(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *WebhookService) GetWebhook(id int) (*model.Webhook, error) {
+	return s.repo.GetWebhook(id)
+}
+
+func (s *WebhookService) ListWebhooks() ([]model.Webhook, error) {
+	return s.repo.ListWebhooks()
+}
+
+// UpdateWebhook replaces the subscription settings. Re-enabling a webhook
+// clears its failure streak.
+func (s *WebhookService) UpdateWebhook(id int, req model.UpdateWebhookRequest) (*model.Webhook, error) {
+	if violations := s.validateWebhook(req.URL, req.EventTypes); len(violations) > 0 {
+		return nil, errors.NewFieldValidationError(violations...)
+	}
+	w, err := s.repo.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Active && !w.Active {
+		w.ConsecutiveFailures = 0
+	}
+	w.URL, w.EventTypes, w.Active = req.URL, req.EventTypes, req.Active
+	if err := s.repo.UpdateWebhook(*w); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWebhook(id)
+}
+
+func (s *WebhookService) DeleteWebhook(id int) error {
+	return s.repo.DeleteWebhook(id)
+}
+
+func (s *WebhookService) ListDeliveries(webhookID int) ([]model.WebhookDelivery, error) {
+	if _, err := s.repo.GetWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(webhookID, 100)
+}
+
+// GetDelivery returns a delivery of the webhook together with its attempt log.
+func (s *WebhookService) GetDelivery(webhookID int, deliveryID int64) (*model.WebhookDeliveryResponse, error) {
+	d, err := s.delivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.ListAttempts(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.WebhookDeliveryResponse{WebhookDelivery: *d, History: history}, nil
+}
+
+// Redeliver queues the delivery to be sent again right away, starting a fresh
+// round of retries.
+func (s *WebhookService) Redeliver(webhookID int, deliveryID int64) (*model.WebhookDelivery, error) {
+	w, err := s.repo.GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !w.Active {
+		return nil, errors.NewValidationError("webhook", "webhook is disabled, re-enable it before redelivering")
+	}
+	d, err := s.delivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	d.Status, d.Attempts, d.NextAttemptAt = model.DeliveryPending, 0, time.Now()
+	if err := s.repo.UpdateDelivery(*d); err != nil {
+		return nil, err
+	}
+	slog.Info("webhook delivery queued for redelivery", "webhook_id", webhookID, "delivery_id", deliveryID)
+	return d, nil
+}
+
+func (s *WebhookService) delivery(webhookID int, deliveryID int64) (*model.WebhookDelivery, error) {
+	d, err := s.repo.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d.WebhookID != webhookID {
+		return nil, errors.NewNotFoundError(deliveryID, "no delivery with that id for this webhook")
+	}
+	return d, nil
+}
+
+func (s *WebhookService) validateWebhook(rawURL string, eventTypes []string) []errors.FieldViolation {
+	var violations []errors.FieldViolation
+	u, err := url.Parse(rawURL)
+	switch {
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		violations = append(violations, errors.FieldViolation{Field: "url", Code: "invalid_url", Message: "must be an absolute http or https URL"})
+	case !s.cfg.AllowPrivateNetworks:
+		if err := webhooks.CheckURL(context.Background(), rawURL); err != nil {
+			violations = append(violations, errors.FieldViolation{Field: "url", Code: "invalid_url", Message: err.Error()})
+		}
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(events.Types, t) {
+			violations = append(violations, errors.FieldViolation{Field: "event_types", Code: "unknown_event_type", Message: fmt.Sprintf("unknown event type %q", t)})
+		}
+	}
+	return violations
+}
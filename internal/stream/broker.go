@@ -0,0 +1,125 @@
+// Package stream fans user events out to live subscribers such as the
+// Server-Sent Events endpoint.
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"user-management/internal/config"
+	"user-management/internal/events"
+)
+
+// streamedTypes are the events live subscribers receive.
+var streamedTypes = map[string]bool{
+	events.TypeUserCreated: true,
+	events.TypeUserUpdated: true,
+	events.TypeUserDeleted: true,
+}
+
+// Broker is an events.Sink that keeps the most recent events in a bounded
+// replay buffer and pushes each new one to every subscriber. Delivery never
+// blocks: a subscriber whose queue is full is dropped, and the client resumes
+// from the replay buffer when it reconnects with Last-Event-ID.
+type Broker struct {
+	mu          sync.Mutex
+	replay      []events.Event
+	seen        map[int64]struct{}
+	replaySize  int
+	queueSize   int
+	horizon     int64
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives events on C until the broker drops it or it is
+// cancelled. C is closed in both cases.
+type Subscription struct {
+	C  <-chan events.Event
+	ch chan events.Event
+}
+
+func NewBroker(cfg config.StreamConfig) *Broker {
+	return &Broker{
+		seen:        map[int64]struct{}{},
+		replaySize:  cfg.ReplaySize,
+		queueSize:   cfg.SubscriberBuffer,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+func (b *Broker) Name() string { return "stream" }
+
+func (b *Broker) Deliver(ctx context.Context, e events.Event) error {
+	if !streamedTypes[e.Type] {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// the dispatcher redelivers events when a later sink fails
+	if _, dup := b.seen[e.ID]; dup {
+		return nil
+	}
+	b.replay = append(b.replay, e)
+	b.seen[e.ID] = struct{}{}
+	if len(b.replay) > b.replaySize {
+		evicted := b.replay[0]
+		b.replay = b.replay[1:]
+		delete(b.seen, evicted.ID)
+		if evicted.ID > b.horizon {
+			b.horizon = evicted.ID
+		}
+	}
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- e:
+		default:
+			slog.Warn("dropping slow event stream subscriber", "event_id", e.ID, "queue_size", b.queueSize)
+			b.remove(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber and returns the buffered events after
+// lastEventID for it to send first. complete is false when the broker cannot
+// tell what came after lastEventID: events after it have already left the
+// buffer, or it was sent before the broker started (such as before a
+// restart) or was never sent at all. The client should then refetch its
+// state instead of relying on the replay. A lastEventID of zero replays
+// nothing.
+func (b *Broker) Subscribe(lastEventID int64) (sub *Subscription, replay []events.Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan events.Event, b.queueSize)
+	sub = &Subscription{C: ch, ch: ch}
+	b.subscribers[sub] = struct{}{}
+	if lastEventID == 0 {
+		return sub, nil, true
+	}
+	for _, e := range b.replay {
+		if e.ID > lastEventID {
+			replay = append(replay, e)
+		}
+	}
+	// every event after the horizon is still buffered, so lastEventID is
+	// known only if it is buffered too or is the last one evicted
+	_, buffered := b.seen[lastEventID]
+	return sub, replay, buffered || (b.horizon != 0 && lastEventID == b.horizon)
+}
+
+// Cancel removes the subscription. It is safe to call after the broker has
+// dropped it.
+func (b *Broker) Cancel(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove closes sub if it is still registered. Callers must hold b.mu.
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.ch)
+}
\ No newline at end of file
@@ -0,0 +1,55 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"user-management/internal/config"
+	"user-management/internal/events"
+)
+
+func deliver(t *testing.T, b *Broker, ids ...int64) {
+	t.Helper()
+	for _, id := range ids {
+		if err := b.Deliver(context.Background(), events.Event{ID: id, Type: events.TypeUserUpdated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSubscribeReplayCompleteness(t *testing.T) {
+	b := NewBroker(config.StreamConfig{ReplaySize: 3, SubscriberBuffer: 8})
+	// a restarted broker: events up to 9 were streamed by the previous process
+	deliver(t, b, 10, 11, 12, 13, 14)
+
+	for _, tc := range []struct {
+		lastEventID int64
+		replayed    int
+		complete    bool
+	}{
+		{lastEventID: 14, replayed: 0, complete: true},
+		{lastEventID: 12, replayed: 2, complete: true},
+		{lastEventID: 11, replayed: 3, complete: true}, // the last one evicted
+		{lastEventID: 10, replayed: 3, complete: false},
+		{lastEventID: 5, replayed: 3, complete: false},  // before the restart
+		{lastEventID: 99, replayed: 0, complete: false}, // never sent
+	} {
+		sub, replay, complete := b.Subscribe(tc.lastEventID)
+		b.Cancel(sub)
+		if len(replay) != tc.replayed || complete != tc.complete {
+			t.Errorf("Subscribe(%d) replayed %d events, complete %v; want %d, %v",
+				tc.lastEventID, len(replay), complete, tc.replayed, tc.complete)
+		}
+	}
+}
+
+func TestSubscribeAfterRestartBeforeEviction(t *testing.T) {
+	b := NewBroker(config.StreamConfig{ReplaySize: 10, SubscriberBuffer: 8})
+	deliver(t, b, 10)
+
+	if _, _, complete := b.Subscribe(9); complete {
+		t.Error("an ID from before the first buffered event was treated as complete")
+	}
+	if _, replay, complete := b.Subscribe(10); !complete || len(replay) != 0 {
+		t.Errorf("Subscribe(10) = %d events, complete %v; want none and complete", len(replay), complete)
+	}
+}
\ No newline at end of file
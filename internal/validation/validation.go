@@ -0,0 +1,145 @@
+// Package validation checks structs against rules declared in `validate`
+// struct tags and reports every violation instead of stopping at the first.
+//
+// Supported rules, comma separated:
+//
+//	required   value must not be empty or whitespace
+//	min=N      at least N characters
+//	max=N      at most N characters
+//	email      a bare address such as user@example.com
+//	username   letters, digits, '.', '_' and '-' only
+//
+// Nil pointer fields are skipped, which lets partial updates reuse the rules.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"user-management/internal/errors"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Struct returns the violations of v, which must be a struct or a pointer to one.
+// Field names come from the json tag so they match what the client sent.
+func Struct(v interface{}) []errors.FieldViolation {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	var violations []errors.FieldViolation
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.String {
+			continue
+		}
+		name := jsonName(sf)
+		for _, rule := range strings.Split(tag, ",") {
+			if v, ok := check(name, rule, fv.String()); !ok {
+				violations = append(violations, v)
+				// the remaining rules would mostly repeat the same complaint
+				if rule == "required" {
+					break
+				}
+			}
+		}
+	}
+	return violations
+}
+
+func check(field, rule, value string) (errors.FieldViolation, bool) {
+	name, arg, _ := strings.Cut(rule, "=")
+	fail := func(code, message string) (errors.FieldViolation, bool) {
+		return errors.FieldViolation{Field: field, Code: code, Message: message}, false
+	}
+	switch name {
+	case "required":
+		if strings.TrimSpace(value) == "" {
+			return fail("required", "is required")
+		}
+	case "min":
+		n, _ := strconv.Atoi(arg)
+		if value != "" && utf8.RuneCountInString(value) < n {
+			return fail("too_short", fmt.Sprintf("must be at least %d characters", n))
+		}
+	case "max":
+		n, _ := strconv.Atoi(arg)
+		if utf8.RuneCountInString(value) > n {
+			return fail("too_long", fmt.Sprintf("must be at most %d characters", n))
+		}
+	case "email":
+		if value != "" && !IsEmail(value) {
+			return fail("invalid_email", "must be a valid email address")
+		}
+	case "username":
+		if value != "" && !isUsername(value) {
+			return fail("invalid_characters", "may only contain letters, digits, '.', '_' and '-'")
+		}
+	}
+	return errors.FieldViolation{}, true
+}
+
+// IsEmail reports whether value is a bare address such as user@example.com.
+func IsEmail(value string) bool {
+	addr, err := mail.ParseAddress(value)
+	// ParseAddress also accepts "Name <a@b.c>"; only the bare form is allowed
+	if err != nil || addr.Address != value {
+		return false
+	}
+	_, domain, _ := strings.Cut(value, "@")
+	return strings.Contains(domain, ".")
+}
+
+func isUsername(value string) bool {
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeEmail trims the address and lowercases the domain, which is
+// case-insensitive everywhere. The local part is only lowercased when
+// lowerLocal is set, since some mail servers treat it as case-sensitive.
+func NormalizeEmail(email string, lowerLocal bool) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if lowerLocal {
+		local = strings.ToLower(local)
+	}
+	return local + "@" + domain
+}
+
+// NormalizeUsername trims the name and applies Unicode NFKC so that
+// look-alike forms such as fullwidth letters collapse to one spelling.
+// Case is kept for display; uniqueness is enforced case-insensitively.
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
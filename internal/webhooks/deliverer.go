@@ -0,0 +1,152 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/model"
+	"user-management/internal/repository"
+)
+
+// Deliverer sends pending webhook deliveries. Failed attempts are retried
+// with exponential backoff until MaxAttempts, and a webhook is disabled once
+// DisableAfter consecutive attempts against it have failed.
+type Deliverer struct {
+	repo   repository.WebhookRepo
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+func NewDeliverer(repo repository.WebhookRepo, cfg config.WebhookConfig) *Deliverer {
+	return &Deliverer{
+		repo:   repo,
+		cfg:    cfg,
+		client: newClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled.
+func (d *Deliverer) Run(ctx context.Context) {
+	slog.Info("webhook deliverer started", "interval", d.cfg.PollInterval.String())
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			slog.Info("webhook deliverer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Deliverer) deliverDue(ctx context.Context) {
+	due, err := d.repo.DueDeliveries(50)
+	if err != nil {
+		slog.Error("unable to fetch due webhook deliveries", "error", err)
+		return
+	}
+	for _, delivery := range due {
+		d.attempt(ctx, delivery)
+	}
+}
+
+func (d *Deliverer) attempt(ctx context.Context, delivery model.WebhookDelivery) {
+	hook, err := d.repo.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		slog.Error("unable to load webhook for delivery", "error", err, "delivery_id", delivery.ID)
+		return
+	}
+	if !hook.Active {
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = "webhook disabled"
+		if err := d.repo.UpdateDelivery(delivery); err != nil {
+			slog.Error("unable to fail delivery of disabled webhook", "error", err, "delivery_id", delivery.ID)
+		}
+		return
+	}
+
+	delivery.Attempts++
+	start := time.Now()
+	status, sendErr := d.send(ctx, hook, delivery)
+	attempt := model.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		StatusCode: status,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	delivery.LastStatusCode = status
+	if sendErr == nil {
+		delivery.Status = model.DeliverySucceeded
+		delivery.LastError = ""
+		if err := d.repo.ResetWebhookFailures(hook.ID); err != nil {
+			slog.Error("unable to reset webhook failure streak", "error", err, "webhook_id", hook.ID)
+		}
+	} else {
+		attempt.Error = sendErr.Error()
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= d.cfg.MaxAttempts {
+			delivery.Status = model.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+		}
+		disabled, err := d.repo.RecordWebhookFailure(hook.ID, d.cfg.DisableAfter)
+		switch {
+		case err != nil:
+			slog.Error("unable to record webhook failure", "error", err, "webhook_id", hook.ID)
+		case disabled:
+			slog.Warn("webhook disabled after repeated failures", "webhook_id", hook.ID, "url", hook.URL)
+		}
+	}
+	if err := d.repo.RecordAttempt(attempt); err != nil {
+		slog.Error("unable to record webhook attempt", "error", err, "delivery_id", delivery.ID, "attempt", attempt.Attempt)
+	}
+	if err := d.repo.UpdateDelivery(delivery); err != nil {
+		// the delivery stays due and is attempted again on the next poll
+		slog.Error("unable to update webhook delivery", "error", err, "delivery_id", delivery.ID, "outcome", delivery.Status)
+		return
+	}
+	slog.Debug("webhook attempt finished", "delivery_id", delivery.ID, "attempt", delivery.Attempts,
+		"status_code", status, "outcome", delivery.Status)
+}
+
+// backoff doubles the wait after every failed attempt, capped at MaxBackoff.
+func (d *Deliverer) backoff(attempts int) time.Duration {
+	wait := d.cfg.RetryBase
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.cfg.MaxBackoff)
+}
+
+func (d *Deliverer) send(ctx context.Context, hook *model.Webhook, delivery model.WebhookDelivery) (int, error) {
+	now := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "user-management-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,248 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/model"
+	"user-management/internal/repository"
+)
+
+// fakeRepo keeps one webhook and the deliveries made to it. Methods the
+// deliverer does not call panic through the nil embedded interface.
+type fakeRepo struct {
+	repository.WebhookRepo
+	mu         sync.Mutex
+	hook       model.Webhook
+	deliveries map[int64]model.WebhookDelivery
+	attempts   []model.WebhookAttempt
+}
+
+func newFakeRepo(url string) *fakeRepo {
+	return &fakeRepo{
+		hook:       model.Webhook{ID: 1, URL: url, Secret: "whsec_test", Active: true},
+		deliveries: map[int64]model.WebhookDelivery{},
+	}
+}
+
+func (r *fakeRepo) add(id int64) {
+	r.deliveries[id] = model.WebhookDelivery{
+		ID:            id,
+		WebhookID:     r.hook.ID,
+		EventID:       id,
+		EventType:     "user.created",
+		Payload:       []byte(`{"id":` + strconv.FormatInt(id, 10) + `}`),
+		Status:        model.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+func (r *fakeRepo) GetWebhook(id int) (*model.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hook := r.hook
+	return &hook, nil
+}
+
+func (r *fakeRepo) RecordWebhookFailure(id int, disableAfter int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hook.ConsecutiveFailures++
+	if r.hook.ConsecutiveFailures >= disableAfter {
+		r.hook.Active = false
+	}
+	return !r.hook.Active, nil
+}
+
+func (r *fakeRepo) ResetWebhookFailures(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hook.ConsecutiveFailures = 0
+	return nil
+}
+
+func (r *fakeRepo) UpdateDelivery(d model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[d.ID] = d
+	return nil
+}
+
+func (r *fakeRepo) RecordAttempt(a model.WebhookAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, a)
+	return nil
+}
+
+func (r *fakeRepo) delivery(id int64) model.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[id]
+}
+
+func testConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		Timeout:              time.Second,
+		MaxAttempts:          3,
+		RetryBase:            time.Minute,
+		MaxBackoff:           3 * time.Minute,
+		DisableAfter:         100,
+		AllowPrivateNetworks: true,
+	}
+}
+
+func TestDeliverySignedForReceiver(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+	repo := newFakeRepo(receiver.URL)
+	repo.add(1)
+	d := NewDeliverer(repo, testConfig())
+
+	d.attempt(context.Background(), repo.delivery(1))
+
+	if got == nil {
+		t.Fatal("receiver got no request")
+	}
+	if got.Header.Get(HeaderEvent) != "user.created" || got.Header.Get(HeaderDelivery) != "1" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	signature, timestamp := got.Header.Get(HeaderSignature), got.Header.Get(HeaderTimestamp)
+	if !Verify("whsec_test", signature, timestamp, body, time.Minute) {
+		t.Errorf("signature %q does not verify", signature)
+	}
+	if Verify("whsec_test", signature, timestamp, append(body, ' '), time.Minute) {
+		t.Error("signature verifies for a modified body")
+	}
+	if Verify("other-secret", signature, timestamp, body, time.Minute) {
+		t.Error("signature verifies with another secret")
+	}
+	if d := repo.delivery(1); d.Status != model.DeliverySucceeded || d.Attempts != 1 {
+		t.Errorf("delivery = %+v, want succeeded after 1 attempt", d)
+	}
+}
+
+func TestFailedDeliveryBacksOffUntilMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+	repo := newFakeRepo(receiver.URL)
+	repo.add(1)
+	cfg := testConfig()
+	d := NewDeliverer(repo, cfg)
+
+	for attempt, wait := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		d.attempt(context.Background(), repo.delivery(1))
+		got := repo.delivery(1)
+		if got.Status != model.DeliveryPending || got.Attempts != attempt+1 || got.LastStatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("after attempt %d: %+v", attempt+1, got)
+		}
+		if next := got.NextAttemptAt.Sub(before); next < wait || next > wait+time.Second {
+			t.Errorf("after attempt %d the next attempt is in %v, want %v", attempt+1, next, wait)
+		}
+	}
+	d.attempt(context.Background(), repo.delivery(1))
+	if got := repo.delivery(1); got.Status != model.DeliveryFailed || got.Attempts != cfg.MaxAttempts {
+		t.Errorf("after max attempts: %+v, want failed", got)
+	}
+	if len(repo.attempts) != cfg.MaxAttempts {
+		t.Errorf("recorded %d attempts, want %d", len(repo.attempts), cfg.MaxAttempts)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	d := NewDeliverer(newFakeRepo(""), testConfig())
+	for attempts, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 3 * time.Minute, 10: 3 * time.Minute} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestWebhookDisabledAfterConsecutiveFailures(t *testing.T) {
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+	repo := newFakeRepo(receiver.URL)
+	for id := int64(1); id <= 3; id++ {
+		repo.add(id)
+	}
+	cfg := testConfig()
+	cfg.DisableAfter = 2
+	d := NewDeliverer(repo, cfg)
+
+	d.attempt(context.Background(), repo.delivery(1))
+	d.attempt(context.Background(), repo.delivery(2))
+	if hook, _ := repo.GetWebhook(1); hook.Active {
+		t.Fatalf("webhook still active after %d failures", hook.ConsecutiveFailures)
+	}
+	d.attempt(context.Background(), repo.delivery(3))
+	if requests != 2 {
+		t.Errorf("receiver got %d requests, want none after the webhook was disabled", requests)
+	}
+	if got := repo.delivery(3); got.Status != model.DeliveryFailed || got.LastError != "webhook disabled" {
+		t.Errorf("delivery to disabled webhook = %+v", got)
+	}
+}
+
+func TestDelivererRefusesInternalAddresses(t *testing.T) {
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer receiver.Close()
+	repo := newFakeRepo(receiver.URL)
+	repo.add(1)
+	cfg := testConfig()
+	cfg.AllowPrivateNetworks = false
+	d := NewDeliverer(repo, cfg)
+
+	d.attempt(context.Background(), repo.delivery(1))
+	if requests != 0 {
+		t.Error("delivery reached a loopback receiver")
+	}
+	if got := repo.delivery(1); !strings.Contains(got.LastError, "internal address") {
+		t.Errorf("last error = %q", got.LastError)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for url, ok := range map[string]bool{
+		"https://hooks.example.com/users": true,
+		"http://93.184.215.14/hook":       true,
+		"ftp://example.com/":              false,
+		"http://localhost:8080/":          false,
+		"http://127.0.0.1/":               false,
+		"http://[::1]/":                   false,
+		"http://169.254.169.254/latest":   false,
+		"http://10.0.0.5/":                false,
+		"http://192.168.1.1/":             false,
+		"http://[::ffff:127.0.0.1]/":      false,
+		"http://0.0.0.0/":                 false,
+	} {
+		if err := CheckURL(context.Background(), url); (err == nil) != ok {
+			t.Errorf("CheckURL(%q) = %v, want ok=%v", url, err, ok)
+		}
+	}
+	if PublicAddr(netip.MustParseAddr("fe80::1")) {
+		t.Error("link-local IPv6 address is public")
+	}
+}
\ No newline at end of file
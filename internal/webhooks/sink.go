@@ -0,0 +1,53 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"user-management/internal/events"
+	"user-management/internal/model"
+	"user-management/internal/repository"
+)
+
+// Sink plugs webhooks into the event dispatcher. It only records a pending
+// delivery per matching subscription; the Deliverer sends them and owns
+// retries, so one slow partner cannot hold up the outbox.
+type Sink struct {
+	repo repository.WebhookRepo
+}
+
+func NewSink(repo repository.WebhookRepo) *Sink {
+	return &Sink{repo: repo}
+}
+
+func (s *Sink) Name() string { return "webhooks" }
+
+func (s *Sink) Deliver(ctx context.Context, e events.Event) error {
+	hooks, err := s.repo.ListSubscribers(e.Type)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	for _, h := range hooks {
+		d := &model.WebhookDelivery{
+			WebhookID:     h.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       body,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		// a failure here makes the dispatcher retry the event; hooks that
+		// already have a delivery for it are skipped by CreateDelivery
+		if err := s.repo.CreateDelivery(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+-- Enforce case-insensitive uniqueness of email and username.
+--
+-- The application lowercases email domains and NFKC-normalizes usernames on
+-- every write, and looks both up with lower(). Rows written before that can
+-- still differ only by case, so they are reported first and the migration
+-- aborts until they are merged or renamed by hand.
+
+DO $$
+DECLARE
+    r record;
+    collisions int := 0;
+BEGIN
+    FOR r IN
+        SELECT lower(email) AS key, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM Users GROUP BY lower(email) HAVING count(*) > 1
+    LOOP
+        RAISE WARNING 'email collision on "%": user ids %', r.key, r.ids;
+        collisions := collisions + 1;
+    END LOOP;
+
+    FOR r IN
+        SELECT lower(username) AS key, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM Users GROUP BY lower(username) HAVING count(*) > 1
+    LOOP
+        RAISE WARNING 'username collision on "%": user ids %', r.key, r.ids;
+        collisions := collisions + 1;
+    END LOOP;
+
+    IF collisions > 0 THEN
+        RAISE EXCEPTION '% case-insensitive collision(s) found, resolve them and rerun', collisions;
+    END IF;
+END $$;
+
+-- Lowercase the domain of existing emails to match what the application stores.
+UPDATE Users
+SET email = split_part(email, '@', 1) || '@' || lower(split_part(email, '@', 2))
+WHERE email LIKE '%@%' AND split_part(email, '@', 2) <> lower(split_part(email, '@', 2));
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON Users (lower(email));
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON Users (lower(username));
@@ -0,0 +1,19 @@
+-- Responses to requests sent with an Idempotency-Key header, kept until
+-- expires_at so retries get the original response. scope is the token
+-- subject, or "anonymous" for unauthenticated requests.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT false,
+    status_code INTEGER,
+    content_type TEXT,
+    location TEXT,
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at);
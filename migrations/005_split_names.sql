@@ -0,0 +1,14 @@
+-- Store given and family names separately for the v2 API. name stays as
+-- the formatted name that v1 reads and writes; the application keeps the
+-- three columns in step on every write.
+--
+-- Existing names are split at the first space, as the application does
+-- for names written through v1.
+
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS given_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS family_name VARCHAR(100) NOT NULL DEFAULT '';
+
+UPDATE Users
+SET given_name = split_part(btrim(name), ' ', 1),
+    family_name = btrim(substr(btrim(name), length(split_part(btrim(name), ' ', 1)) + 1))
+WHERE coalesce(btrim(name), '') <> '' AND given_name = '' AND family_name = '';
@@ -0,0 +1,18 @@
+-- Password age and history, used by the password policy since they were
+-- added to the schema in the Readme. Databases created before then have
+-- neither; existing passwords count as changed when this runs.
+--
+-- password_changed_at was first documented as TIMESTAMP. The type change
+-- reads those values in the session time zone, which is how
+-- CURRENT_TIMESTAMP wrote them, and is a no-op when it is TIMESTAMPTZ.
+
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE Users ALTER COLUMN password_changed_at TYPE TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES Users(id) ON DELETE CASCADE,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS password_history_user_idx ON password_history (user_id, created_at DESC);
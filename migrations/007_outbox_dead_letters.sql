@@ -0,0 +1,9 @@
+-- Events the dispatcher gave up on after OUTBOX_MAX_ATTEMPTS failed
+-- deliveries. They keep their last_error and are no longer fetched, so
+-- they cannot hold back later events. To retry one, clear failed_at and
+-- reset attempts.
+
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE delivered_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,12 @@
+-- One delivery per webhook and event, for databases created before
+-- migrations/003 declared webhook_deliveries_event_key. Duplicates recorded
+-- while the dispatcher retried an event are removed first, keeping the
+-- earliest delivery and its attempt log.
+
+DELETE FROM webhook_deliveries d
+USING webhook_deliveries keep
+WHERE d.webhook_id = keep.webhook_id
+  AND d.event_id = keep.event_id
+  AND d.id > keep.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_event_key ON webhook_deliveries (webhook_id, event_id);
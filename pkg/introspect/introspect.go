@@ -0,0 +1,170 @@
+// Package introspect is a client for the user service's token introspection
+// endpoint (RFC 7662). Services use it to check user tokens without knowing
+// the signing secret. Answers are cached briefly, so a revoked token may be
+// accepted for up to the cache TTL.
+package introspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response is the introspection answer. Only Active is set for tokens that
+// are invalid, expired, revoked or belong to a deactivated user.
+type Response struct {
+	Active    bool     `json:"active"`
+	Subject   string   `json:"sub,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+}
+
+// Scopes splits the space separated scope.
+func (r *Response) Scopes() []string {
+	return strings.Fields(r.Scope)
+}
+
+const (
+	DefaultCacheTTL    = 30 * time.Second
+	DefaultCacheSize   = 10000
+	defaultHTTPTimeout = 5 * time.Second
+)
+
+type Client struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	ttl          time.Duration
+	size         int
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]entry
+}
+
+type entry struct {
+	res     *Response
+	expires time.Time
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(client *Client) { client.httpClient = c }
+}
+
+// WithCache sets how long answers are reused and how many are kept. A ttl
+// of zero disables caching.
+func WithCache(ttl time.Duration, size int) Option {
+	return func(client *Client) { client.ttl, client.size = ttl, size }
+}
+
+// New returns a client for endpoint, e.g.
+// https://users.internal/oauth2/introspect, that authenticates with the
+// given client credentials.
+func New(endpoint, clientID, clientSecret string, opts ...Option) *Client {
+	c := &Client{
+		endpoint:     endpoint,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: defaultHTTPTimeout},
+		ttl:          DefaultCacheTTL,
+		size:         DefaultCacheSize,
+		cache:        map[[sha256.Size]byte]entry{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Introspect returns the state of token. An inactive token is not an error;
+// errors mean the answer could not be obtained.
+func (c *Client) Introspect(ctx context.Context, token string) (*Response, error) {
+	// tokens are secrets, so the cache is keyed by their hash
+	key := sha256.Sum256([]byte(token))
+	if res, ok := c.cached(key); ok {
+		return res, nil
+	}
+	res, err := c.fetch(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, res)
+	return res, nil
+}
+
+func (c *Client) fetch(ctx context.Context, token string) (*Response, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build introspection request %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned %s", resp.Status)
+	}
+	var res Response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("unable to decode introspection response %w", err)
+	}
+	return &res, nil
+}
+
+func (c *Client) cached(key [sha256.Size]byte) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.cache[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.res, true
+}
+
+// store keeps res for the TTL, but never past the token's own expiry.
+func (c *Client) store(key [sha256.Size]byte, res *Response) {
+	if c.ttl <= 0 || c.size <= 0 {
+		return
+	}
+	expires := time.Now().Add(c.ttl)
+	if res.Active && res.ExpiresAt > 0 {
+		if exp := time.Unix(res.ExpiresAt, 0); exp.Before(expires) {
+			expires = exp
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache) >= c.size {
+		now := time.Now()
+		for k, e := range c.cache {
+			if now.After(e.expires) {
+				delete(c.cache, k)
+			}
+		}
+		// still full: drop arbitrary entries, they are cheap to fetch again
+		for k := range c.cache {
+			if len(c.cache) < c.size {
+				break
+			}
+			delete(c.cache, k)
+		}
+	}
+	c.cache[key] = entry{res: res, expires: expires}
+}
\ No newline at end of file
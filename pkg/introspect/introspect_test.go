@@ -0,0 +1,125 @@
+package introspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newServer answers every introspection request with res and counts them.
+func newServer(t *testing.T, res Response) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "orders" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestIntrospectCachesAnswers(t *testing.T) {
+	srv, calls := newServer(t, Response{Active: true, Subject: "42", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	c := New(srv.URL, "orders", "s3cret")
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Introspect(context.Background(), "token-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Active || res.Subject != "42" {
+			t.Fatalf("got %+v, want the active answer for subject 42", res)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("endpoint called %d times for one token, want 1", n)
+	}
+
+	if _, err := c.Introspect(context.Background(), "token-b"); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("endpoint called %d times for two tokens, want 2", n)
+	}
+}
+
+func TestIntrospectWithoutCache(t *testing.T) {
+	srv, calls := newServer(t, Response{Active: true})
+	c := New(srv.URL, "orders", "s3cret", WithCache(0, 0))
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Introspect(context.Background(), "token"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("endpoint called %d times, want 2 with caching disabled", n)
+	}
+}
+
+func TestCacheNeverOutlivesToken(t *testing.T) {
+	exp := time.Now().Add(10 * time.Second).Truncate(time.Second)
+	srv, _ := newServer(t, Response{Active: true, ExpiresAt: exp.Unix()})
+	c := New(srv.URL, "orders", "s3cret", WithCache(time.Hour, 10))
+
+	if _, err := c.Introspect(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := c.cache[sha256.Sum256([]byte("token"))]
+	if !ok {
+		t.Fatal("answer was not cached")
+	}
+	if !e.expires.Equal(exp) {
+		t.Errorf("cached until %v, want the token expiry %v", e.expires, exp)
+	}
+
+	// an expired entry is fetched again
+	e.expires = time.Now().Add(-time.Second)
+	c.cache[sha256.Sum256([]byte("token"))] = e
+	if _, ok := c.cached(sha256.Sum256([]byte("token"))); ok {
+		t.Error("expired entry was served from the cache")
+	}
+}
+
+func TestInactiveIsNotAnError(t *testing.T) {
+	srv, _ := newServer(t, Response{Active: false})
+	c := New(srv.URL, "orders", "s3cret")
+
+	res, err := c.Introspect(context.Background(), "revoked")
+	if err != nil {
+		t.Fatalf("inactive token returned error %v", err)
+	}
+	if res.Active {
+		t.Errorf("got %+v, want an inactive answer", res)
+	}
+}
+
+func TestIntrospectErrors(t *testing.T) {
+	srv, calls := newServer(t, Response{Active: true})
+
+	// wrong credentials: the endpoint answers 401, which is an error and
+	// is not cached
+	c := New(srv.URL, "orders", "wrong")
+	for i := 0; i < 2; i++ {
+		if res, err := c.Introspect(context.Background(), "token"); err == nil {
+			t.Fatalf("got %+v, want an error for a 401 answer", res)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("endpoint called %d times, want 2 since errors are not cached", n)
+	}
+}
\ No newline at end of file